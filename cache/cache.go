@@ -1,7 +1,9 @@
 package cache
 
 import (
-	"fmt"
+	"encoding/json"
+	"slices"
+	"sync/atomic"
 
 	"github.com/aichy126/igo/config"
 	"github.com/redis/go-redis/v9"
@@ -24,27 +26,63 @@ func NewCache(conf *config.Config) (*Cache, error) {
 	return cache, nil
 }
 
+// Redis 一个 redis 实例(单节点/哨兵/集群),内嵌 redis.UniversalClient,
+// 三种部署模式下的命令调用方式完全一致
 type Redis struct {
-	*redis.Client
-	State   RedisState `json:"state"`
-	Options *redis.Options
+	redis.UniversalClient
+	Mode      string `json:"mode"`
+	Options   *redis.UniversalOptions
+	KeyPrefix string `json:"key_prefix"` // 配置的 key_prefix,命令中的 key 会自动加上该前缀
+
+	state atomic.Int32 // RedisState,由 RedisManager 的后台探测更新
+
+	shareKey string // 创建时的配置(不含配置名),配置完全相同的才共用实例
 }
 type RedisState int
 
+func (s RedisState) String() string {
+	if s == DownServer {
+		return "down"
+	}
+	return "active"
+}
+
 const (
 	ActiveServer = RedisState(0)
 	DownServer   = RedisState(1)
 )
 
-func NewRedis(client *redis.Client, options *redis.Options) *Redis {
+// NewRedis 包装一个已创建的 go-redis 客户端,*redis.Client/*redis.ClusterClient 均可传入
+func NewRedis(client redis.UniversalClient, options *redis.UniversalOptions) *Redis {
+	mode := ModeStandalone
+	if _, ok := client.(*redis.ClusterClient); ok {
+		mode = ModeCluster
+	} else if options != nil && options.MasterName != "" {
+		mode = ModeSentinel
+	}
 	return &Redis{
-		Client:  client,
-		Options: options,
-		State:   ActiveServer,
+		UniversalClient: client,
+		Options:         options,
+		Mode:            mode,
 	}
 }
 
-func (r Redis) IsEqual(options *redis.Options) bool {
+// State 返回实例当前状态(ActiveServer/DownServer)
+func (r *Redis) State() RedisState {
+	return RedisState(r.state.Load())
+}
+
+// IsDown 实例是否已被后台探测标记为不可用
+func (r *Redis) IsDown() bool {
+	return r.State() == DownServer
+}
+
+// setState 更新状态,返回状态是否发生了变化
+func (r *Redis) setState(s RedisState) bool {
+	return RedisState(r.state.Swap(int32(s))) != s
+}
+
+func (r *Redis) IsEqual(options *redis.UniversalOptions) bool {
 	if options == nil {
 		return false
 	}
@@ -52,18 +90,44 @@ func (r Redis) IsEqual(options *redis.Options) bool {
 		return false
 	}
 
-	if r.Options.Addr == options.Addr && r.Options.DB == options.DB {
-		return true
-	}
-	return false
+	return slices.Equal(r.Options.Addrs, options.Addrs) &&
+		r.Options.DB == options.DB &&
+		r.Options.MasterName == options.MasterName &&
+		r.Options.Username == options.Username &&
+		(r.Options.TLSConfig == nil) == (options.TLSConfig == nil) &&
+		r.Options.IsClusterMode == options.IsClusterMode
 }
 
 func (this *Redis) MarshalJSON() ([]byte, error) {
-	return []byte(fmt.Sprintf(`{"state":%d,"address":"%s","db":%d}`, this.State, this.Options.Addr, this.Options.DB)), nil
+	var addrs []string
+	var db int
+	if this.Options != nil {
+		addrs, db = this.Options.Addrs, this.Options.DB
+	}
+	return json.Marshal(struct {
+		State     RedisState `json:"state"`
+		Mode      string     `json:"mode"`
+		Addresses []string   `json:"addresses"`
+		DB        int        `json:"db"`
+		KeyPrefix string     `json:"key_prefix,omitempty"`
+	}{this.State(), this.Mode, addrs, db, this.KeyPrefix})
 }
 
+// Key 返回 key 在 redis 中的实际名称(加上 key_prefix)
+// 命令参数中的 key 会自动加前缀,只有 Lua 脚本内部拼接的 key、日志排查等场景需要手动调用
+func (r *Redis) Key(key string) string {
+	return r.KeyPrefix + key
+}
+
+// GetClient 返回单节点/哨兵模式下的 *redis.Client;集群模式返回 nil,请使用 GetUniversalClient
 func (r *Redis) GetClient() *redis.Client {
-	return r.Client
+	client, _ := r.UniversalClient.(*redis.Client)
+	return client
+}
+
+// GetUniversalClient 返回底层客户端,三种部署模式通用
+func (r *Redis) GetUniversalClient() redis.UniversalClient {
+	return r.UniversalClient
 }
 
 // Close 关闭所有缓存连接
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aichy126/igo/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrRedisDown 实例已被健康探测标记为不可用
+// fail_fast 实例上的命令和 GetHealthy 在实例不可用时返回包装了它的错误,可用 errors.Is 判断
+var ErrRedisDown = errors.New("redis 实例已被标记为不可用")
+
+// StateListener 实例状态变化回调;err 为导致变为 DownServer 的最后一次探测错误,恢复时为 nil
+type StateListener func(name string, state RedisState, err error)
+
+// probeCtxKey 标记探测/健康检查发起的命令,fail_fast 不拦截它们,否则实例永远无法恢复
+type probeCtxKey struct{}
+
+func withProbe(ctx context.Context) context.Context {
+	return context.WithValue(ctx, probeCtxKey{}, true)
+}
+
+func isProbe(ctx context.Context) bool {
+	v, _ := ctx.Value(probeCtxKey{}).(bool)
+	return v
+}
+
+// failFastHook 实例不可用时直接返回错误,不再等待连接/读写超时
+type failFastHook struct {
+	r *Redis
+}
+
+func (h failFastHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h failFastHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if h.r.IsDown() && !isProbe(ctx) {
+			cmd.SetErr(ErrRedisDown)
+			return ErrRedisDown
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h failFastHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if h.r.IsDown() && !isProbe(ctx) {
+			for _, cmd := range cmds {
+				cmd.SetErr(ErrRedisDown)
+			}
+			return ErrRedisDown
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// OnStateChange 注册实例状态变化回调(回调在探测 goroutine 中同步执行,不要阻塞)
+// 状态变化同时会记录日志:不可用为 Error 级别(会触发 log.AddHook 注册的告警钩子),恢复为 Warn 级别
+func (rm *RedisManager) OnStateChange(listener StateListener) {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	rm.listeners = append(rm.listeners, listener)
+}
+
+// GetHealthy 获取可用的实例:主实例正常时返回主实例;
+// 主实例被标记为不可用时,返回配置的 fallback 实例(需同样可用),否则返回包装了 ErrRedisDown 的错误
+func (rm *RedisManager) GetHealthy(name string) (*Redis, error) {
+	r, err := rm.get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !r.IsDown() {
+		return r, nil
+	}
+
+	rm.mutex.RLock()
+	fallback := rm.configs[name].Fallback
+	rm.mutex.RUnlock()
+	if fallback == "" {
+		return nil, fmt.Errorf("redis [%s]: %w", name, ErrRedisDown)
+	}
+	fr, err := rm.get(fallback)
+	if err != nil {
+		return nil, err
+	}
+	if fr.IsDown() {
+		return nil, fmt.Errorf("redis [%s] 及其 fallback [%s]: %w", name, fallback, ErrRedisDown)
+	}
+	return fr, nil
+}
+
+// startProbes 为每个开启探测的实例启动后台探测 goroutine(多个配置名共享同一连接时只探测一次)
+func (rm *RedisManager) startProbes() {
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	ctx, cancel := context.WithCancel(context.Background())
+	rm.probeCancel = cancel
+
+	probed := make(map[*Redis]bool)
+	for name, r := range rm.resources {
+		rc := rm.configs[name]
+		if rc.ProbeInterval <= 0 || probed[r] {
+			continue
+		}
+		probed[r] = true
+		rm.probeWG.Add(1)
+		go rm.probe(ctx, name, r, rc)
+	}
+}
+
+// stopProbes 停止所有探测 goroutine 并等待退出
+func (rm *RedisManager) stopProbes() {
+	if rm.probeCancel != nil {
+		rm.probeCancel()
+	}
+	rm.probeWG.Wait()
+}
+
+func (rm *RedisManager) probe(ctx context.Context, name string, r *Redis, rc redisConfig) {
+	defer rm.probeWG.Done()
+	interval := time.Duration(rc.ProbeInterval) * time.Millisecond
+	timeout := min(interval, 2*time.Second)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		pingCtx, cancel := context.WithTimeout(withProbe(ctx), timeout)
+		err := r.Ping(pingCtx).Err()
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			failures = 0
+			if r.setState(ActiveServer) {
+				log.Warn("redis 实例已恢复", log.String("name", name), log.String("address", strings.Join(rc.Addresses, ",")))
+				rm.notify(name, ActiveServer, nil)
+			}
+			continue
+		}
+
+		failures++
+		if failures >= rc.ProbeFailures && r.setState(DownServer) {
+			log.Error("redis 实例不可用",
+				log.String("name", name),
+				log.String("address", strings.Join(rc.Addresses, ",")),
+				log.Int("failures", failures),
+				log.Any("error", err),
+			)
+			rm.notify(name, DownServer, err)
+		}
+	}
+}
+
+func (rm *RedisManager) notify(name string, state RedisState, err error) {
+	rm.mutex.RLock()
+	listeners := make([]StateListener, len(rm.listeners))
+	copy(listeners, rm.listeners)
+	rm.mutex.RUnlock()
+
+	for _, listener := range listeners {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Error("redis 状态回调 panic", log.String("name", name), log.Any("panic", r))
+				}
+			}()
+			listener(name, state, err)
+		}()
+	}
+}
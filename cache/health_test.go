@@ -0,0 +1,223 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/config"
+	"github.com/alicebob/miniredis/v2"
+)
+
+// newTestConfig 把 toml 内容写入临时文件并加载
+func newTestConfig(t *testing.T, content string) *config.Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := config.NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conf
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestProbeFailFastAndFallback 验证探测翻转状态、fail_fast 快速失败、GetHealthy 降级与恢复
+func TestProbeFailFastAndFallback(t *testing.T) {
+	primary := miniredis.RunT(t)
+	backup := miniredis.RunT(t)
+	conf := newTestConfig(t, fmt.Sprintf(`
+[redis.primary]
+address = %q
+probe_interval = 20
+probe_failures = 2
+fail_fast = true
+fallback = "backup"
+
+[redis.backup]
+address = %q
+probe_interval = -1
+`, primary.Addr(), backup.Addr()))
+
+	rm, err := NewRedisManager(conf)
+	if err != nil {
+		t.Fatalf("NewRedisManager error: %v", err)
+	}
+	defer rm.Close()
+
+	var mu sync.Mutex
+	var transitions []RedisState
+	rm.OnStateChange(func(name string, state RedisState, err error) {
+		mu.Lock()
+		transitions = append(transitions, state)
+		mu.Unlock()
+	})
+	stateCount := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(transitions)
+	}
+
+	r, _ := rm.Get("primary")
+	if r.State() != ActiveServer {
+		t.Fatalf("初始状态应为 ActiveServer")
+	}
+
+	primary.SetError("LOADING simulated outage")
+	waitFor(t, "primary 被标记为不可用", r.IsDown)
+
+	if err := r.Get(t.Context(), "k").Err(); !errors.Is(err, ErrRedisDown) {
+		t.Errorf("fail_fast 实例不可用时应返回 ErrRedisDown, got %v", err)
+	}
+	healthy, err := rm.GetHealthy("primary")
+	if err != nil {
+		t.Fatalf("GetHealthy error: %v", err)
+	}
+	if b, _ := rm.Get("backup"); healthy != b {
+		t.Error("primary 不可用时 GetHealthy 应返回 backup")
+	}
+	// 健康检查绕过 fail_fast,反映真实连通性
+	if err := rm.PingAll(t.Context())["primary"]; err == nil || errors.Is(err, ErrRedisDown) {
+		t.Errorf("PingAll 应真实访问 redis, got %v", err)
+	}
+
+	primary.SetError("")
+	waitFor(t, "primary 恢复", func() bool { return !r.IsDown() })
+	waitFor(t, "两次状态回调", func() bool { return stateCount() == 2 })
+	if healthy, _ := rm.GetHealthy("primary"); healthy != r {
+		t.Error("恢复后 GetHealthy 应返回 primary")
+	}
+	mu.Lock()
+	if transitions[0] != DownServer || transitions[1] != ActiveServer {
+		t.Errorf("transitions = %v", transitions)
+	}
+	mu.Unlock()
+}
+
+func TestFallbackValidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	conf := newTestConfig(t, fmt.Sprintf(`
+[redis.primary]
+address = %q
+fallback = "missing"
+`, mr.Addr()))
+	if _, err := NewRedisManager(conf); err == nil {
+		t.Error("fallback 指向不存在的实例应报错")
+	}
+}
+
+func TestGetHealthyWithoutFallback(t *testing.T) {
+	mr := miniredis.RunT(t)
+	conf := newTestConfig(t, fmt.Sprintf(`
+[redis.only]
+address = %q
+probe_interval = 20
+probe_failures = 1
+`, mr.Addr()))
+	rm, err := NewRedisManager(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm.Close()
+	r, _ := rm.Get("only")
+
+	mr.SetError("ERR down")
+	waitFor(t, "only 被标记为不可用", r.IsDown)
+	if _, err := rm.GetHealthy("only"); !errors.Is(err, ErrRedisDown) {
+		t.Errorf("无 fallback 时应返回 ErrRedisDown, got %v", err)
+	}
+	// 未开启 fail_fast 时命令照常发往 redis
+	if err := r.Get(t.Context(), "k").Err(); errors.Is(err, ErrRedisDown) {
+		t.Error("未开启 fail_fast 不应拦截命令")
+	}
+}
+
+// TestSharedInstanceRequiresSameHealthConfig 连接参数相同但 fail_fast/探测配置不同的配置不能共用实例
+func TestSharedInstanceRequiresSameHealthConfig(t *testing.T) {
+	mr := miniredis.RunT(t)
+	conf := newTestConfig(t, fmt.Sprintf(`
+[redis.strict]
+address = %q
+fail_fast = true
+
+[redis.lenient]
+address = %q
+probe_interval = 1000
+
+[redis.lenient_copy]
+address = %q
+probe_interval = 1000
+`, mr.Addr(), mr.Addr(), mr.Addr()))
+	rm, err := NewRedisManager(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm.Close()
+	strict, _ := rm.Get("strict")
+	lenient, _ := rm.Get("lenient")
+	lenientCopy, _ := rm.Get("lenient_copy")
+	if strict == lenient {
+		t.Error("fail_fast 与探测配置不同的配置不能共用实例")
+	}
+	if lenient != lenientCopy {
+		t.Error("配置完全相同时应共用实例")
+	}
+}
+
+// TestSharedInstanceRequiresSameConnConfig 地址相同但连接池、密码不同的配置不能共用实例
+func TestSharedInstanceRequiresSameConnConfig(t *testing.T) {
+	mr := miniredis.RunT(t)
+	conf := newTestConfig(t, fmt.Sprintf(`
+[redis.small]
+address = %q
+poolsize = 5
+
+[redis.large]
+address = %q
+poolsize = 50
+
+[redis.plain]
+address = %q
+
+[redis.auth]
+address = %q
+password = "secret"
+`, mr.Addr(), mr.Addr(), mr.Addr(), mr.Addr()))
+	rm, err := NewRedisManager(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm.Close()
+	small, _ := rm.Get("small")
+	large, _ := rm.Get("large")
+	if small == large {
+		t.Error("poolsize 不同的配置不能共用实例")
+	}
+	if small.Options.PoolSize != 5 || large.Options.PoolSize != 50 {
+		t.Errorf("poolsize 未生效: %d %d", small.Options.PoolSize, large.Options.PoolSize)
+	}
+	plain, _ := rm.Get("plain")
+	auth, _ := rm.Get("auth")
+	if plain == auth {
+		t.Error("password 不同的配置不能共用实例")
+	}
+	if auth.Options.Password != "secret" {
+		t.Errorf("password 未生效: %q", auth.Options.Password)
+	}
+}
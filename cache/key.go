@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// KeyPart 可以作为 key 组成部分的类型
+type KeyPart interface {
+	~string | ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// keyTemplate 以 {} 为占位符的 key 模板,如 "user:{}:profile";
+// 带内容的 {tag} 是 redis 集群的 hash tag,原样保留,"{{}}" 表示用参数作为 hash tag
+type keyTemplate struct {
+	pattern string
+	parts   []string // 按占位符切分后的固定部分,len = 占位符个数 + 1
+}
+
+func newKeyTemplate(pattern string, n int) keyTemplate {
+	parts := strings.Split(pattern, "{}")
+	if len(parts) != n+1 {
+		panic(fmt.Sprintf("key 模板 %q 需要 %d 个 {} 占位符,实际 %d 个", pattern, n, len(parts)-1))
+	}
+	return keyTemplate{pattern: pattern, parts: parts}
+}
+
+func (t keyTemplate) build(vals ...string) string {
+	var b strings.Builder
+	for i, p := range t.parts {
+		b.WriteString(p)
+		if i < len(vals) {
+			b.WriteString(vals[i])
+		}
+	}
+	return b.String()
+}
+
+// keyPartString 按底层类型格式化,自定义类型(如 type UserID int64)的 String 方法不参与 key 生成
+func keyPartString[T KeyPart](v T) string {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10)
+	default:
+		return strconv.FormatUint(rv.Uint(), 10)
+	}
+}
+
+// Key1 一个参数的 key 模板,参数类型在编译期检查
+//
+//	var userKey = cache.NewKey1[int64]("user:{}")
+//	rds.Get(ctx, userKey.Key(uid))
+type Key1[A KeyPart] struct{ t keyTemplate }
+
+// NewKey1 创建一个参数的 key 模板,占位符个数不符时 panic(通常定义为包级变量,启动即暴露)
+func NewKey1[A KeyPart](pattern string) Key1[A] {
+	return Key1[A]{newKeyTemplate(pattern, 1)}
+}
+
+// Key 生成 key
+func (k Key1[A]) Key(a A) string {
+	return k.t.build(keyPartString(a))
+}
+
+// String 返回模板本身
+func (k Key1[A]) String() string { return k.t.pattern }
+
+// Key2 两个参数的 key 模板
+//
+//	var orderItemKey = cache.NewKey2[int64, string]("order:{}:item:{}")
+type Key2[A, B KeyPart] struct{ t keyTemplate }
+
+// NewKey2 创建两个参数的 key 模板
+func NewKey2[A, B KeyPart](pattern string) Key2[A, B] {
+	return Key2[A, B]{newKeyTemplate(pattern, 2)}
+}
+
+// Key 生成 key
+func (k Key2[A, B]) Key(a A, b B) string {
+	return k.t.build(keyPartString(a), keyPartString(b))
+}
+
+// String 返回模板本身
+func (k Key2[A, B]) String() string { return k.t.pattern }
+
+// Key3 三个参数的 key 模板
+type Key3[A, B, C KeyPart] struct{ t keyTemplate }
+
+// NewKey3 创建三个参数的 key 模板
+func NewKey3[A, B, C KeyPart](pattern string) Key3[A, B, C] {
+	return Key3[A, B, C]{newKeyTemplate(pattern, 3)}
+}
+
+// Key 生成 key
+func (k Key3[A, B, C]) Key(a A, b B, c C) string {
+	return k.t.build(keyPartString(a), keyPartString(b), keyPartString(c))
+}
+
+// String 返回模板本身
+func (k Key3[A, B, C]) String() string { return k.t.pattern }
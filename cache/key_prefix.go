@@ -0,0 +1,287 @@
+package cache
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPos 命令中 key 参数的位置规则
+type keyPos int
+
+const (
+	keyFirst        keyPos = iota // args[1]
+	keyFirstTwo                   // args[1]、args[2]
+	keyAll                        // args[1:]
+	keyAllButLast                 // args[1:len-1],如 BLPOP k1 k2 timeout
+	keyPairs                      // args[1]、args[3]……,如 MSET k1 v1 k2 v2
+	keySecond                     // args[2],子命令形式,如 XGROUP CREATE key
+	keyFromSecond                 // args[2:],如 BITOP AND dest k1 k2
+	keyNumkeys1                   // args[1] 为 key 个数,如 ZUNION numkeys k1 k2
+	keyNumkeys2                   // args[2] 为 key 个数,如 EVAL script numkeys k1 k2
+	keyDestNumkeys2               // args[1] 为目标 key,args[2] 为 key 个数,如 ZUNIONSTORE dest numkeys k1 k2
+	keyStreams                    // STREAMS 之后的前一半参数,如 XREAD STREAMS k1 k2 id1 id2
+)
+
+// keyCommands 需要加前缀的命令及其 key 位置,不在表中的命令原样发送
+var keyCommands = func() map[string]keyPos {
+	m := make(map[string]keyPos)
+	add := func(pos keyPos, cmds ...string) {
+		for _, c := range cmds {
+			m[c] = pos
+		}
+	}
+	add(keyFirst,
+		// string
+		"get", "set", "setnx", "setex", "psetex", "getset", "getdel", "getex", "append", "strlen",
+		"incr", "incrby", "incrbyfloat", "decr", "decrby", "getrange", "setrange",
+		"getbit", "setbit", "bitcount", "bitpos", "bitfield", "bitfield_ro",
+		// key
+		"expire", "pexpire", "expireat", "pexpireat", "expiretime", "pexpiretime",
+		"ttl", "pttl", "persist", "type", "dump", "restore", "sort", "sort_ro",
+		// hash
+		"hget", "hset", "hsetnx", "hmget", "hmset", "hdel", "hexists", "hgetall", "hkeys", "hvals",
+		"hlen", "hincrby", "hincrbyfloat", "hscan", "hstrlen", "hrandfield",
+		"hexpire", "hpexpire", "hexpireat", "hpexpireat", "httl", "hpttl", "hpersist", "hgetdel", "hgetex", "hsetex",
+		// list
+		"lpush", "rpush", "lpushx", "rpushx", "lpop", "rpop", "llen", "lrange", "lindex",
+		"lset", "lrem", "ltrim", "linsert", "lpos",
+		// set
+		"sadd", "srem", "smembers", "sismember", "smismember", "scard", "spop", "srandmember", "sscan",
+		// sorted set
+		"zadd", "zrem", "zscore", "zmscore", "zincrby", "zcard", "zcount", "zlexcount",
+		"zrange", "zrangebyscore", "zrangebylex", "zrevrange", "zrevrangebyscore", "zrevrangebylex",
+		"zrank", "zrevrank", "zremrangebyscore", "zremrangebyrank", "zremrangebylex",
+		"zscan", "zrandmember", "zpopmin", "zpopmax",
+		// stream
+		"xadd", "xlen", "xrange", "xrevrange", "xdel", "xtrim", "xack", "xpending", "xclaim", "xautoclaim", "xsetid",
+		// hyperloglog / geo
+		"pfadd", "geoadd", "geopos", "geodist", "geohash", "georadius", "georadiusbymember",
+		"georadius_ro", "georadiusbymember_ro", "geosearch",
+	)
+	add(keyFirstTwo, "rename", "renamenx", "copy", "smove", "rpoplpush", "lmove", "blmove", "brpoplpush",
+		"zrangestore", "geosearchstore", "lcs")
+	add(keyAll, "del", "unlink", "exists", "touch", "mget", "watch",
+		"sinter", "sunion", "sdiff", "sinterstore", "sunionstore", "sdiffstore", "pfcount", "pfmerge")
+	add(keyAllButLast, "blpop", "brpop", "bzpopmin", "bzpopmax")
+	add(keyPairs, "mset", "msetnx")
+	add(keySecond, "xgroup", "xinfo", "object", "memory")
+	add(keyFromSecond, "bitop")
+	add(keyNumkeys1, "zunion", "zinter", "zdiff", "zintercard", "sintercard", "lmpop", "zmpop")
+	add(keyNumkeys2, "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro", "blmpop", "bzmpop")
+	add(keyDestNumkeys2, "zunionstore", "zinterstore", "zdiffstore")
+	add(keyStreams, "xread", "xreadgroup")
+	return m
+}()
+
+// keyPrefixHook 给命令中的 key 加上前缀,实现共用 Redis 时的 key 隔离;
+// 也会改写 KEYS/SCAN 的匹配模式,并去掉 KEYS/SCAN/BLPOP/XREAD 等返回结果中的前缀。
+// pub/sub 的 channel 不在 key 的范畴,不加前缀。
+type keyPrefixHook struct {
+	prefix string
+}
+
+func (h keyPrefixHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h keyPrefixHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		restore := h.rewrite(cmd)
+		err := next(ctx, cmd)
+		h.strip(cmd)
+		restore()
+		return err
+	}
+}
+
+func (h keyPrefixHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		restores := make([]func(), len(cmds))
+		for i, cmd := range cmds {
+			restores[i] = h.rewrite(cmd)
+		}
+		err := next(ctx, cmds)
+		for i, cmd := range cmds {
+			h.strip(cmd)
+			restores[i]()
+		}
+		return err
+	}
+}
+
+func noop() {}
+
+// rewrite 原地改写 cmd 参数中的 key,返回恢复原参数的函数;
+// 命令执行后恢复,同一个 cmd 被再次执行(如 ScanIterator 翻页)时不会重复加前缀
+func (h keyPrefixHook) rewrite(cmd redis.Cmder) func() {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return noop
+	}
+	name := strings.ToLower(cmd.Name())
+	var idx []int
+	switch name {
+	case "keys":
+		idx = []int{1}
+	case "scan":
+		for i := 2; i+1 < len(args); i++ {
+			if s, ok := args[i].(string); ok && strings.EqualFold(s, "match") {
+				idx = append(idx, i+1)
+				break
+			}
+		}
+	default:
+		pos, ok := keyCommands[name]
+		if !ok {
+			return noop
+		}
+		idx = keyIndexes(pos, args)
+	}
+	if len(idx) == 0 {
+		return noop
+	}
+
+	orig := make([]any, len(idx))
+	for i, j := range idx {
+		orig[i] = args[j]
+		if s, ok := args[j].(string); ok {
+			args[j] = h.prefix + s
+		}
+	}
+	return func() {
+		for i, j := range idx {
+			args[j] = orig[i]
+		}
+	}
+}
+
+// keyIndexes 根据位置规则计算 key 参数的下标
+func keyIndexes(pos keyPos, args []any) []int {
+	n := len(args)
+	var idx []int
+	switch pos {
+	case keyFirst:
+		idx = []int{1}
+	case keyFirstTwo:
+		idx = []int{1}
+		if n > 2 {
+			idx = append(idx, 2)
+		}
+	case keyAll:
+		for i := 1; i < n; i++ {
+			idx = append(idx, i)
+		}
+	case keyAllButLast:
+		for i := 1; i < n-1; i++ {
+			idx = append(idx, i)
+		}
+	case keyPairs:
+		for i := 1; i < n; i += 2 {
+			idx = append(idx, i)
+		}
+	case keySecond:
+		if n > 2 {
+			idx = []int{2}
+		}
+	case keyFromSecond:
+		for i := 2; i < n; i++ {
+			idx = append(idx, i)
+		}
+	case keyNumkeys1:
+		idx = numkeysIndexes(args, 1)
+	case keyNumkeys2:
+		idx = numkeysIndexes(args, 2)
+	case keyDestNumkeys2:
+		idx = append([]int{1}, numkeysIndexes(args, 2)...)
+	case keyStreams:
+		for i := 1; i < n; i++ {
+			if s, ok := args[i].(string); ok && strings.EqualFold(s, "streams") {
+				rest := n - i - 1
+				for j := i + 1; j <= i+rest/2; j++ {
+					idx = append(idx, j)
+				}
+				break
+			}
+		}
+	}
+	return idx
+}
+
+// numkeysIndexes args[at] 为 key 个数,其后紧跟对应个数的 key
+func numkeysIndexes(args []any, at int) []int {
+	if at >= len(args) {
+		return nil
+	}
+	var num int
+	switch v := args[at].(type) {
+	case int:
+		num = v
+	case int64:
+		num = int(v)
+	case string:
+		num, _ = strconv.Atoi(v)
+	}
+	if num <= 0 {
+		return nil
+	}
+	idx := make([]int, 0, num)
+	for i := at + 1; i <= at+num && i < len(args); i++ {
+		idx = append(idx, i)
+	}
+	return idx
+}
+
+// strip 去掉返回结果中的 key 前缀
+func (h keyPrefixHook) strip(cmd redis.Cmder) {
+	if cmd.Err() != nil {
+		return
+	}
+	switch c := cmd.(type) {
+	case *redis.StringSliceCmd:
+		switch strings.ToLower(c.Name()) {
+		case "keys":
+			c.SetVal(h.trimAll(c.Val()))
+		case "blpop", "brpop":
+			if val := c.Val(); len(val) > 0 {
+				val[0] = strings.TrimPrefix(val[0], h.prefix)
+			}
+		}
+	case *redis.ScanCmd:
+		if strings.EqualFold(c.Name(), "scan") {
+			keys, cursor := c.Val()
+			c.SetVal(h.trimAll(keys), cursor)
+		}
+	case *redis.ZWithKeyCmd:
+		if val := c.Val(); val != nil {
+			val.Key = strings.TrimPrefix(val.Key, h.prefix)
+		}
+	case *redis.KeyValuesCmd:
+		key, vals := c.Val()
+		c.SetVal(strings.TrimPrefix(key, h.prefix), vals)
+	case *redis.ZSliceWithKeyCmd:
+		key, vals := c.Val()
+		c.SetVal(strings.TrimPrefix(key, h.prefix), vals)
+	case *redis.XStreamSliceCmd:
+		val := c.Val()
+		for i := range val {
+			val[i].Stream = strings.TrimPrefix(val[i].Stream, h.prefix)
+		}
+	}
+}
+
+// trimAll 去掉前缀,不带前缀的 key(属于其他业务)被过滤掉
+func (h keyPrefixHook) trimAll(keys []string) []string {
+	out := keys[:0]
+	for _, k := range keys {
+		if rest, ok := strings.CutPrefix(k, h.prefix); ok {
+			out = append(out, rest)
+		}
+	}
+	return out
+}
@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newPrefixedRedis(t *testing.T, mr *miniredis.Miniredis) (*Cache, *Redis) {
+	t.Helper()
+	conf := newTestConfig(t, fmt.Sprintf(`
+[redis.a]
+address = %q
+key_prefix = "svc:"
+probe_interval = -1
+
+[redis.b]
+address = %q
+probe_interval = -1
+`, mr.Addr(), mr.Addr()))
+	c, err := NewCache(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	r, err := c.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, r
+}
+
+func TestKeyPrefixCommands(t *testing.T) {
+	mr := miniredis.RunT(t)
+	c, r := newPrefixedRedis(t, mr)
+	ctx := t.Context()
+
+	if r.KeyPrefix != "svc:" || r.Key("k") != "svc:k" {
+		t.Fatalf("KeyPrefix = %q", r.KeyPrefix)
+	}
+	plain, _ := c.Get("b")
+	if plain == r {
+		t.Fatal("key_prefix 不同的配置不能共用实例")
+	}
+
+	if err := r.Set(ctx, "k", "v", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := mr.Get("svc:k"); v != "v" {
+		t.Errorf("redis 中的 key 应带前缀, svc:k = %q", v)
+	}
+	if v, _ := r.Get(ctx, "k").Result(); v != "v" {
+		t.Errorf("Get = %q", v)
+	}
+
+	// 多 key 命令
+	r.MSet(ctx, "a", "1", "b", "2")
+	if vals, _ := r.MGet(ctx, "a", "b").Result(); !slices.Equal(vals, []any{"1", "2"}) {
+		t.Errorf("MGet = %v", vals)
+	}
+	if !mr.Exists("svc:a") || !mr.Exists("svc:b") {
+		t.Error("MSET 的 key 应带前缀")
+	}
+	if n, _ := r.Del(ctx, "a", "b").Result(); n != 2 {
+		t.Errorf("Del = %d, want 2", n)
+	}
+
+	// Lua 脚本的 KEYS
+	script := redis.NewScript(`return redis.call('GET', KEYS[1])`)
+	if v, err := script.Run(ctx, r, []string{"k"}).Result(); err != nil || v != "v" {
+		t.Errorf("script = %v, %v", v, err)
+	}
+
+	// 管道和事务
+	if _, err := r.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Set(ctx, "p1", "1", 0)
+		p.Incr(ctx, "p2")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, "h", "f", "1")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"svc:p1", "svc:p2", "svc:h"} {
+		if !mr.Exists(k) {
+			t.Errorf("管道命令的 key %s 不存在", k)
+		}
+	}
+}
+
+func TestKeyPrefixResults(t *testing.T) {
+	mr := miniredis.RunT(t)
+	_, r := newPrefixedRedis(t, mr)
+	ctx := t.Context()
+
+	_ = mr.Set("other:x", "1") // 其他业务的 key
+	for _, k := range []string{"u:1", "u:2", "u:3"} {
+		r.Set(ctx, k, "1", 0)
+	}
+
+	keys, _ := r.Keys(ctx, "u:*").Result()
+	slices.Sort(keys)
+	if !slices.Equal(keys, []string{"u:1", "u:2", "u:3"}) {
+		t.Errorf("Keys = %v", keys)
+	}
+
+	// ScanIterator 复用同一个 cmd 翻页,不能重复加前缀
+	var scanned []string
+	iter := r.Scan(ctx, 0, "u:*", 1).Iterator()
+	for iter.Next(ctx) {
+		scanned = append(scanned, iter.Val())
+	}
+	slices.Sort(scanned)
+	if !slices.Equal(scanned, []string{"u:1", "u:2", "u:3"}) {
+		t.Errorf("Scan = %v, err = %v", scanned, iter.Err())
+	}
+
+	r.RPush(ctx, "list", "a")
+	if v, _ := r.BLPop(ctx, time.Second, "list").Result(); !slices.Equal(v, []string{"list", "a"}) {
+		t.Errorf("BLPop = %v", v)
+	}
+
+	r.XAdd(ctx, &redis.XAddArgs{Stream: "s", Values: []string{"f", "v"}})
+	streams, err := r.XRead(ctx, &redis.XReadArgs{Streams: []string{"s", "0"}, Count: 1}).Result()
+	if err != nil || len(streams) != 1 || streams[0].Stream != "s" {
+		t.Errorf("XRead = %+v, %v", streams, err)
+	}
+	if !mr.Exists("svc:s") {
+		t.Error("stream key 应带前缀")
+	}
+}
+
+func TestKeyIndexes(t *testing.T) {
+	cases := []struct {
+		args []any
+		want []int
+	}{
+		{[]any{"get", "k"}, []int{1}},
+		{[]any{"blpop", "a", "b", 0}, []int{1, 2}},
+		{[]any{"mset", "a", 1, "b", 2}, []int{1, 3}},
+		{[]any{"eval", "return 1", 2, "a", "b", "arg"}, []int{3, 4}},
+		{[]any{"zunionstore", "dest", 2, "a", "b", "weights", 1, 2}, []int{1, 3, 4}},
+		{[]any{"xreadgroup", "group", "g", "c", "count", 1, "streams", "a", "b", ">", ">"}, []int{7, 8}},
+		{[]any{"xgroup", "create", "s", "g", "0"}, []int{2}},
+		{[]any{"bitop", "and", "dest", "a", "b"}, []int{2, 3, 4}},
+	}
+	for _, c := range cases {
+		pos := keyCommands[c.args[0].(string)]
+		if got := keyIndexes(pos, c.args); !slices.Equal(got, c.want) {
+			t.Errorf("%v: got %v, want %v", c.args, got, c.want)
+		}
+	}
+}
+
+func TestKeyTemplate(t *testing.T) {
+	type userID int64
+	k1 := NewKey1[userID]("user:{}")
+	if got := k1.Key(42); got != "user:42" {
+		t.Errorf("Key1 = %q", got)
+	}
+	k2 := NewKey2[string, uint8]("order:{{}}:item:{}")
+	if got := k2.Key("a1", 3); got != "order:{a1}:item:3" {
+		t.Errorf("Key2 = %q", got)
+	}
+	k3 := NewKey3[int, string, int]("{tag}:{}:{}:{}")
+	if got := k3.Key(1, "x", -2); got != "{tag}:1:x:-2" {
+		t.Errorf("Key3 = %q", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("占位符个数不符应 panic")
+		}
+	}()
+	NewKey2[int, int]("a:{}")
+}
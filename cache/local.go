@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// localCache 进程内 LRU 缓存:按字节数限制容量,每个 key 有独立过期时间,并发安全
+// 占用字节按 len(key)+len(value) 估算,不含 map/链表自身开销
+type localCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	used     int64
+	ll       *list.List
+	items    map[string]*list.Element
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+type localEntry struct {
+	key      string
+	value    string
+	expireAt time.Time // 零值表示不过期
+}
+
+func (e *localEntry) size() int64 {
+	return int64(len(e.key) + len(e.value))
+}
+
+func newLocalCache(maxBytes int64) *localCache {
+	return &localCache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get 命中且未过期时返回值,并把 key 移到队头;过期的条目顺带删除
+func (lc *localCache) get(key string) (string, bool) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	el, ok := lc.items[key]
+	if !ok {
+		lc.misses++
+		return "", false
+	}
+	entry := el.Value.(*localEntry)
+	if !entry.expireAt.IsZero() && time.Now().After(entry.expireAt) {
+		lc.removeElement(el)
+		lc.misses++
+		return "", false
+	}
+	lc.ll.MoveToFront(el)
+	lc.hits++
+	return entry.value, true
+}
+
+// set 写入 key,ttl<=0 表示不过期;单条超过容量上限的值不缓存
+func (lc *localCache) set(key, value string, ttl time.Duration) {
+	entry := &localEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expireAt = time.Now().Add(ttl)
+	}
+
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if el, ok := lc.items[key]; ok {
+		lc.removeElement(el)
+	}
+	if entry.size() > lc.maxBytes {
+		return
+	}
+	lc.items[key] = lc.ll.PushFront(entry)
+	lc.used += entry.size()
+	for lc.used > lc.maxBytes {
+		lc.removeElement(lc.ll.Back())
+		lc.evictions++
+	}
+}
+
+func (lc *localCache) delete(keys ...string) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := lc.items[key]; ok {
+			lc.removeElement(el)
+		}
+	}
+}
+
+func (lc *localCache) clear() {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.ll.Init()
+	lc.items = make(map[string]*list.Element)
+	lc.used = 0
+}
+
+func (lc *localCache) removeElement(el *list.Element) {
+	entry := lc.ll.Remove(el).(*localEntry)
+	delete(lc.items, entry.key)
+	lc.used -= entry.size()
+}
+
+// LocalStats 本地缓存统计
+type LocalStats struct {
+	Entries   int   `json:"entries"`
+	Bytes     int64 `json:"bytes"`
+	MaxBytes  int64 `json:"max_bytes"`
+	Hits      int64 `json:"hits"`
+	Misses    int64 `json:"misses"`
+	Evictions int64 `json:"evictions"` // 因容量不足被淘汰的条目数(不含过期和主动删除)
+}
+
+func (lc *localCache) stats() LocalStats {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	return LocalStats{
+		Entries:   len(lc.items),
+		Bytes:     lc.used,
+		MaxBytes:  lc.maxBytes,
+		Hits:      lc.hits,
+		Misses:    lc.misses,
+		Evictions: lc.evictions,
+	}
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	icontext "github.com/aichy126/igo/context"
+	"github.com/aichy126/igo/log"
+	"github.com/aichy126/igo/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+// redis 命令耗时分桶(秒),比默认分桶更细,覆盖亚毫秒级
+var redisBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1}
+
+// 标签 redis 为配置名;不用 instance,避免与 Prometheus 抓取时附加的 instance 标签冲突
+var (
+	redisCmdDuration = metrics.NewHistogramVec("igo_redis_command_duration_seconds",
+		"redis 命令耗时(秒),管道按整体记为 pipeline", redisBuckets, "redis", "command")
+	redisCmdErrors = metrics.NewCounterVec("igo_redis_command_errors_total",
+		"redis 命令错误数(不含 redis.Nil)", "redis", "command")
+)
+
+// blockingCommands 阻塞类命令,耗时由调用方的超时参数决定,不记慢日志
+var blockingCommands = map[string]bool{
+	"blpop": true, "brpop": true, "blmove": true, "brpoplpush": true, "blmpop": true,
+	"bzpopmin": true, "bzpopmax": true, "bzmpop": true, "xread": true, "xreadgroup": true, "wait": true,
+}
+
+// metricsHook 记录命令耗时、错误数,并记录慢命令日志
+type metricsHook struct {
+	instance string
+	slow     time.Duration // <= 0 不记录慢命令
+}
+
+func (h metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		elapsed := time.Since(start)
+
+		name := cmd.Name()
+		redisCmdDuration.WithLabelValues(h.instance, name).Observe(elapsed.Seconds())
+		if isRedisError(err) {
+			redisCmdErrors.WithLabelValues(h.instance, name).Inc()
+		}
+		if h.slow > 0 && elapsed >= h.slow && !blockingCommands[name] {
+			h.logSlow(ctx, name, slowKey(cmd), elapsed, 1)
+		}
+		return err
+	}
+}
+
+func (h metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		elapsed := time.Since(start)
+
+		redisCmdDuration.WithLabelValues(h.instance, "pipeline").Observe(elapsed.Seconds())
+		for _, cmd := range cmds {
+			if isRedisError(cmd.Err()) {
+				redisCmdErrors.WithLabelValues(h.instance, cmd.Name()).Inc()
+			}
+		}
+		if h.slow > 0 && elapsed >= h.slow {
+			h.logSlow(ctx, "pipeline", "", elapsed, len(cmds))
+		}
+		return err
+	}
+}
+
+func (h metricsHook) logSlow(ctx context.Context, name, key string, elapsed time.Duration, count int) {
+	fields := []log.Field{
+		log.String("instance", h.instance),
+		log.String("command", name),
+		log.Any("duration", elapsed.String()),
+	}
+	if key != "" {
+		fields = append(fields, log.String("key", key))
+	}
+	if count > 1 {
+		fields = append(fields, log.Int("commands", count))
+	}
+	if ictx, ok := ctx.(icontext.IContext); ok {
+		fields = append(fields, log.String("traceId", ictx.GetString("traceId")))
+	}
+	log.Warn("redis 慢命令", fields...)
+}
+
+// slowKey 慢日志只记录命令的第一个 key(截断),不记录 value,避免日志过大或泄露敏感数据。
+// key 位置与 key 前缀使用同一张表(EVAL 的脚本、XGROUP 的子命令等不是 key)
+func slowKey(cmd redis.Cmder) string {
+	args := cmd.Args()
+	pos, ok := keyCommands[strings.ToLower(cmd.Name())]
+	if !ok || len(args) < 2 {
+		return ""
+	}
+	idx := keyIndexes(pos, args)
+	if len(idx) == 0 {
+		return ""
+	}
+	key, _ := args[idx[0]].(string)
+	if len(key) > 128 {
+		key = key[:128] + "..."
+	}
+	return key
+}
+
+func isRedisError(err error) bool {
+	return err != nil && !errors.Is(err, redis.Nil)
+}
+
+// PoolStats 连接池统计
+type PoolStats struct {
+	Hits       uint32 `json:"hits"`        // 从池中拿到空闲连接的次数
+	Misses     uint32 `json:"misses"`      // 池中没有空闲连接、新建连接的次数
+	Timeouts   uint32 `json:"timeouts"`    // 等待连接超时的次数
+	TotalConns uint32 `json:"total_conns"` // 当前连接总数
+	IdleConns  uint32 `json:"idle_conns"`  // 当前空闲连接数
+	StaleConns uint32 `json:"stale_conns"` // 被清理的过期连接数
+}
+
+func newPoolStats(s *redis.PoolStats) PoolStats {
+	return PoolStats{
+		Hits:       s.Hits,
+		Misses:     s.Misses,
+		Timeouts:   s.Timeouts,
+		TotalConns: s.TotalConns,
+		IdleConns:  s.IdleConns,
+		StaleConns: s.StaleConns,
+	}
+}
+
+// PoolStats 返回所有实例的连接池统计
+func (rm *RedisManager) PoolStats() map[string]PoolStats {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+	stats := make(map[string]PoolStats, len(rm.resources))
+	for name, r := range rm.resources {
+		stats[name] = newPoolStats(r.UniversalClient.PoolStats())
+	}
+	return stats
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMetricsHook(t *testing.T) {
+	mr := miniredis.RunT(t)
+	conf := newTestConfig(t, fmt.Sprintf(`
+[redis.metrics_a]
+address = %q
+key_prefix = "svc:"
+slow_threshold = 100
+probe_interval = -1
+`, mr.Addr()))
+	c, err := NewCache(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	r, _ := c.Get("metrics_a")
+	ctx := t.Context()
+
+	get := redisCmdDuration.WithLabelValues("metrics_a", "get")
+	getErrs := redisCmdErrors.WithLabelValues("metrics_a", "get")
+	before := get.Count()
+
+	r.Get(ctx, "missing") // redis.Nil 不算错误
+	r.Set(ctx, "h", "v", 0)
+	r.HSet(ctx, "hash", "f", "v")
+	r.Get(ctx, "hash") // WRONGTYPE
+	if n := get.Count() - before; n != 2 {
+		t.Errorf("get 耗时记录 %d 次, want 2", n)
+	}
+	if v := getErrs.Value(); v != 1 {
+		t.Errorf("get 错误数 = %v, want 1", v)
+	}
+
+	pipe := redisCmdDuration.WithLabelValues("metrics_a", "pipeline")
+	before = pipe.Count()
+	_, _ = r.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Incr(ctx, "n")
+		p.Incr(ctx, "hash") // WRONGTYPE
+		return nil
+	})
+	if pipe.Count()-before != 1 {
+		t.Error("管道应按整体记录一次耗时")
+	}
+	if v := redisCmdErrors.WithLabelValues("metrics_a", "incr").Value(); v != 1 {
+		t.Errorf("管道中 incr 错误数 = %v, want 1", v)
+	}
+
+	stats := c.PoolStats()
+	if s, ok := stats["metrics_a"]; !ok || s.TotalConns == 0 {
+		t.Errorf("PoolStats = %+v", stats)
+	}
+}
+
+func TestMetricsHookSlow(t *testing.T) {
+	h := metricsHook{instance: "slow_test", slow: 10 * time.Millisecond}
+	process := h.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	cmd := redis.NewStringCmd(context.Background(), "get", "k")
+	if err := process(context.Background(), cmd); err != nil {
+		t.Fatal(err)
+	}
+	if h := redisCmdDuration.WithLabelValues("slow_test", "get"); h.Count() != 1 || h.Sum() < 0.02 {
+		t.Errorf("耗时记录 count=%d sum=%v", h.Count(), h.Sum())
+	}
+}
+
+func TestSlowKey(t *testing.T) {
+	ctx := context.Background()
+	long := string(make([]byte, 200))
+	cases := []struct {
+		cmd  redis.Cmder
+		want string
+	}{
+		{redis.NewStringCmd(ctx, "get", "user:1"), "user:1"},
+		{redis.NewStatusCmd(ctx, "set", long, "v"), long[:128] + "..."},
+		{redis.NewStatusCmd(ctx, "ping"), ""},
+		{redis.NewStringCmd(ctx, "info", "server"), ""},
+		{redis.NewCmd(ctx, "eval", "return redis.call('get', KEYS[1])", 1, "lock:1"), "lock:1"},
+		{redis.NewCmd(ctx, "evalsha", "e0e1f9fabfc9d4800c877a703b823ac0578ff831", 0), ""},
+		{redis.NewStatusCmd(ctx, "xgroup", "create", "orders", "g1", "$"), "orders"},
+		{redis.NewIntCmd(ctx, "bitop", "and", "dest", "k1", "k2"), "dest"},
+		{redis.NewIntCmd(ctx, "del", "a", "b"), "a"},
+	}
+	for _, c := range cases {
+		if got := slowKey(c.cmd); got != c.want {
+			t.Errorf("%v: slowKey = %q, want %q", c.cmd.Args(), got, c.want)
+		}
+	}
+}
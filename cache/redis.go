@@ -3,6 +3,7 @@ package cache
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,18 +13,26 @@ import (
 type RedisManager struct {
 	mutex     sync.RWMutex
 	resources map[string]*Redis
+	configs   map[string]redisConfig
+	listeners []StateListener
+
+	probeCancel context.CancelFunc
+	probeWG     sync.WaitGroup
 }
 
 // NewRedisManager 从配置初始化所有 redis 连接
 // 配置了的 redis 必须连接成功(ping),否则返回错误(fail-fast);
 // 完全没有 [redis] 配置时返回空 manager,不报错。
+// 初始化成功后为每个实例启动后台健康探测(probe_interval),Close 时停止。
 func NewRedisManager(conf *config.Config) (*RedisManager, error) {
 	r := &RedisManager{
 		resources: make(map[string]*Redis),
+		configs:   make(map[string]redisConfig),
 	}
 	if err := r.initRedis(conf); err != nil {
 		return nil, err
 	}
+	r.startProbes()
 	return r, nil
 }
 
@@ -54,12 +63,13 @@ func (rm *RedisManager) Names() []string {
 }
 
 // PingAll 对所有 redis 执行 Ping,返回每个实例的结果(nil 表示正常)
+// 会真实访问 redis,不受 fail_fast 影响
 func (rm *RedisManager) PingAll(ctx context.Context) map[string]error {
 	rm.mutex.RLock()
 	defer rm.mutex.RUnlock()
 	result := make(map[string]error, len(rm.resources))
 	for name, r := range rm.resources {
-		result[name] = r.Ping(ctx).Err()
+		result[name] = r.Ping(withProbe(ctx)).Err()
 	}
 	return result
 }
@@ -77,6 +87,7 @@ func (rm *RedisManager) initRedis(conf *config.Config) error {
 		if err := rc.parse(itemRedisConfig); err != nil {
 			return fmt.Errorf("redis 配置 [redis.%s] 解析失败: %w", name, err)
 		}
+		rc.name = name
 		r, err := rm.newRedis(rc)
 		if err != nil {
 			return fmt.Errorf("redis [%s] 初始化失败: %w", name, err)
@@ -86,30 +97,46 @@ func (rm *RedisManager) initRedis(conf *config.Config) error {
 		err = r.Ping(pingCtx).Err()
 		cancel()
 		if err != nil {
-			return fmt.Errorf("redis [%s] 连接失败(ping %s): %w", name, rc.Address, err)
+			return fmt.Errorf("redis [%s] 连接失败(ping %s): %w", name, strings.Join(rc.Addresses, ","), err)
 		}
 		rm.resources[name] = r
+		rm.configs[name] = rc
+	}
+	for name, rc := range rm.configs {
+		if rc.Fallback == "" {
+			continue
+		}
+		if rc.Fallback == name {
+			return fmt.Errorf("redis 配置 [redis.%s] 的 fallback 不能指向自身", name)
+		}
+		if _, ok := rm.configs[rc.Fallback]; !ok {
+			return fmt.Errorf("redis 配置 [redis.%s] 的 fallback [%s] 不存在", name, rc.Fallback)
+		}
 	}
 	return nil
 }
 
 func (rm *RedisManager) newRedis(config redisConfig) (*Redis, error) {
+	// 连接池、超时、认证、TLS、hook 与探测配置都相同才共用实例,否则后初始化的配置会被静默忽略;
+	// 共用实例时指标的 redis 标签为先初始化的配置名
+	key := config.shareKey()
 	for _, r := range rm.resources {
-		if r.IsEqual(config.toOptions()) {
+		if r.shareKey == key {
 			return r, nil
 		}
 	}
 	return config.newRedis()
 }
 
-// Close 关闭所有Redis连接
+// Close 停止健康探测并关闭所有Redis连接
 func (rm *RedisManager) Close() error {
+	rm.stopProbes()
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
 
 	for name, r := range rm.resources {
-		if r != nil && r.Client != nil {
-			if err := r.Client.Close(); err != nil {
+		if r != nil && r.UniversalClient != nil {
+			if err := r.UniversalClient.Close(); err != nil {
 				fmt.Printf("关闭Redis连接失败 name=%s error=%v\n", name, err)
 			}
 		}
@@ -1,7 +1,11 @@
 package cache
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/aichy126/igo/log"
@@ -11,14 +15,50 @@ import (
 	"time"
 )
 
+// redis 部署模式
+const (
+	ModeStandalone = "standalone" // 单节点(默认)
+	ModeSentinel   = "sentinel"   // 哨兵,addresses 填哨兵地址,需指定 master_name
+	ModeCluster    = "cluster"    // 集群,addresses 填任意若干节点地址
+)
+
 type redisConfig struct {
-	Address      string `json:"address" toml:"address" mapstructure:"address"`
-	Password     string `json:"password" toml:"password" mapstructure:"password"`
-	DB           int    `json:"db" toml:"db" mapstructure:"db"`
-	PoolSize     int    `json:"poolsize" toml:"poolsize" mapstructure:"poolsize"`
-	DialTimeout  int    `json:"dial_timeout" toml:"dial_timeout" mapstructure:"dial_timeout"`    // 毫秒
-	ReadTimeout  int    `json:"read_timeout" toml:"read_timeout" mapstructure:"read_timeout"`    // 毫秒
-	WriteTimeout int    `json:"write_timeout" toml:"write_timeout" mapstructure:"write_timeout"` // 毫秒
+	Mode             string   `json:"mode" toml:"mode" mapstructure:"mode"`
+	Address          string   `json:"address" toml:"address" mapstructure:"address"`
+	Addresses        []string `json:"addresses" toml:"addresses" mapstructure:"addresses"`
+	MasterName       string   `json:"master_name" toml:"master_name" mapstructure:"master_name"`
+	SentinelPassword string   `json:"sentinel_password" toml:"sentinel_password" mapstructure:"sentinel_password"`
+	Password         string   `json:"password" toml:"password" mapstructure:"password"`
+	DB               int      `json:"db" toml:"db" mapstructure:"db"`
+	PoolSize         int      `json:"poolsize" toml:"poolsize" mapstructure:"poolsize"`
+	DialTimeout      int      `json:"dial_timeout" toml:"dial_timeout" mapstructure:"dial_timeout"`    // 毫秒
+	ReadTimeout      int      `json:"read_timeout" toml:"read_timeout" mapstructure:"read_timeout"`    // 毫秒
+	WriteTimeout     int      `json:"write_timeout" toml:"write_timeout" mapstructure:"write_timeout"` // 毫秒
+	KeyPrefix        string   `json:"key_prefix" toml:"key_prefix" mapstructure:"key_prefix"`          // 所有命令的 key 自动加上该前缀,多个业务共用一个 redis 时隔离 key
+
+	Username        string `json:"username" toml:"username" mapstructure:"username"` // ACL 用户名(redis 6+)
+	MinIdleConns    int    `json:"min_idle_conns" toml:"min_idle_conns" mapstructure:"min_idle_conns"`
+	MaxRetries      int    `json:"max_retries" toml:"max_retries" mapstructure:"max_retries"`                      // -1 表示不重试,0 使用 go-redis 默认值(3)
+	PoolTimeout     int    `json:"pool_timeout" toml:"pool_timeout" mapstructure:"pool_timeout"`                   // 毫秒
+	ConnMaxLifetime int    `json:"conn_max_lifetime" toml:"conn_max_lifetime" mapstructure:"conn_max_lifetime"`    // 毫秒
+	ConnMaxIdleTime int    `json:"conn_max_idle_time" toml:"conn_max_idle_time" mapstructure:"conn_max_idle_time"` // 毫秒
+
+	TLS                   bool   `json:"tls" toml:"tls" mapstructure:"tls"`
+	TLSCAFile             string `json:"tls_ca_file" toml:"tls_ca_file" mapstructure:"tls_ca_file"`       // 自定义 CA(PEM),为空使用系统根证书
+	TLSCertFile           string `json:"tls_cert_file" toml:"tls_cert_file" mapstructure:"tls_cert_file"` // 客户端证书(双向 TLS),需与 tls_key_file 同时配置
+	TLSKeyFile            string `json:"tls_key_file" toml:"tls_key_file" mapstructure:"tls_key_file"`
+	TLSServerName         string `json:"tls_server_name" toml:"tls_server_name" mapstructure:"tls_server_name"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify" toml:"tls_insecure_skip_verify" mapstructure:"tls_insecure_skip_verify"`
+
+	ProbeInterval int    `json:"probe_interval" toml:"probe_interval" mapstructure:"probe_interval"` // 毫秒,健康探测间隔,默认 5000,-1 关闭探测
+	ProbeFailures int    `json:"probe_failures" toml:"probe_failures" mapstructure:"probe_failures"` // 连续探测失败多少次标记为不可用,默认 3
+	FailFast      bool   `json:"fail_fast" toml:"fail_fast" mapstructure:"fail_fast"`                // 标记为不可用后命令直接返回 ErrRedisDown,不再等待超时
+	Fallback      string `json:"fallback" toml:"fallback" mapstructure:"fallback"`                   // 不可用时 GetHealthy 降级使用的实例名
+
+	SlowThreshold int `json:"slow_threshold" toml:"slow_threshold" mapstructure:"slow_threshold"` // 毫秒,命令耗时超过该值记录慢日志,0 不记录
+
+	name      string      // 配置名,作为指标的 redis 标签和慢日志的 instance
+	tlsConfig *tls.Config // parse 时根据 tls_* 配置构建
 }
 
 func (rc redisConfig) String() string {
@@ -27,35 +67,236 @@ func (rc redisConfig) String() string {
 }
 
 func (rc *redisConfig) parse(conf *redisConfig) error {
+	rc.Mode = strings.ToLower(strings.TrimSpace(conf.Mode))
 	rc.Address = strings.TrimSpace(conf.Address)
+	rc.MasterName = strings.TrimSpace(conf.MasterName)
+	rc.SentinelPassword = strings.TrimSpace(conf.SentinelPassword)
 	rc.Password = strings.TrimSpace(conf.Password)
 	rc.DB = conf.DB
 	rc.PoolSize = conf.PoolSize
 	rc.DialTimeout = conf.DialTimeout
 	rc.ReadTimeout = conf.ReadTimeout
 	rc.WriteTimeout = conf.WriteTimeout
-	if !strings.Contains(rc.Address, ":") {
-		log.Warn("redis address 未指定端口,使用默认端口 6379", log.Any("address", rc.Address))
-		rc.Address = rc.Address + ":6379"
+	rc.KeyPrefix = conf.KeyPrefix
+	rc.Username = strings.TrimSpace(conf.Username)
+	rc.MinIdleConns = conf.MinIdleConns
+	rc.MaxRetries = conf.MaxRetries
+	rc.PoolTimeout = conf.PoolTimeout
+	rc.ConnMaxLifetime = conf.ConnMaxLifetime
+	rc.ConnMaxIdleTime = conf.ConnMaxIdleTime
+	rc.TLS = conf.TLS
+	rc.TLSCAFile = strings.TrimSpace(conf.TLSCAFile)
+	rc.TLSCertFile = strings.TrimSpace(conf.TLSCertFile)
+	rc.TLSKeyFile = strings.TrimSpace(conf.TLSKeyFile)
+	rc.TLSServerName = strings.TrimSpace(conf.TLSServerName)
+	rc.TLSInsecureSkipVerify = conf.TLSInsecureSkipVerify
+	rc.ProbeInterval = conf.ProbeInterval
+	rc.ProbeFailures = conf.ProbeFailures
+	rc.FailFast = conf.FailFast
+	rc.Fallback = strings.TrimSpace(conf.Fallback)
+	rc.SlowThreshold = conf.SlowThreshold
+	if rc.ProbeInterval == 0 {
+		rc.ProbeInterval = 5000
+	}
+	if rc.ProbeFailures <= 0 {
+		rc.ProbeFailures = 3
+	}
+	if rc.ProbeInterval < -1 {
+		return fmt.Errorf("probe_interval 只能为 -1(关闭)或正数: %d", rc.ProbeInterval)
+	}
+	if rc.FailFast && rc.ProbeInterval < 0 {
+		return fmt.Errorf("fail_fast 依赖健康探测,不能与 probe_interval = -1 同时使用")
+	}
+
+	if err := rc.validatePool(); err != nil {
+		return err
+	}
+	tlsConfig, err := rc.buildTLSConfig()
+	if err != nil {
+		return err
+	}
+	rc.tlsConfig = tlsConfig
+
+	// address 与 addresses 可混用,统一归并到 Addresses
+	rc.Addresses = make([]string, 0, len(conf.Addresses)+1)
+	if rc.Address != "" {
+		rc.Addresses = append(rc.Addresses, rc.Address)
+	}
+	for _, addr := range conf.Addresses {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			rc.Addresses = append(rc.Addresses, addr)
+		}
+	}
+	for i, addr := range rc.Addresses {
+		if !strings.Contains(addr, ":") {
+			log.Warn("redis address 未指定端口,使用默认端口 6379", log.Any("address", addr))
+			rc.Addresses[i] = addr + ":6379"
+		}
+	}
+
+	switch rc.Mode {
+	case "", ModeStandalone:
+		rc.Mode = ModeStandalone
+		if len(rc.Addresses) == 0 {
+			return fmt.Errorf("standalone 模式缺少 address")
+		}
+		if len(rc.Addresses) > 1 {
+			return fmt.Errorf("standalone 模式只能配置一个地址,多个地址请使用 mode = %q 或 %q", ModeCluster, ModeSentinel)
+		}
+		rc.Address = rc.Addresses[0]
+	case ModeSentinel:
+		if len(rc.Addresses) == 0 {
+			return fmt.Errorf("sentinel 模式缺少哨兵地址 addresses")
+		}
+		if rc.MasterName == "" {
+			return fmt.Errorf("sentinel 模式缺少 master_name")
+		}
+	case ModeCluster:
+		if len(rc.Addresses) == 0 {
+			return fmt.Errorf("cluster 模式缺少节点地址 addresses")
+		}
+		if rc.DB != 0 {
+			return fmt.Errorf("cluster 模式不支持 db = %d,只能使用 db 0", rc.DB)
+		}
+	default:
+		return fmt.Errorf("不支持的 mode %q,可选 %s/%s/%s", rc.Mode, ModeStandalone, ModeSentinel, ModeCluster)
 	}
 	return nil
 }
 
-func (rc redisConfig) toOptions() *redis.Options {
-	options := &redis.Options{
-		Addr:         rc.Address,
-		Password:     rc.Password,
-		DB:           rc.DB,
-		PoolSize:     rc.PoolSize,
-		DialTimeout:  time.Duration(rc.DialTimeout) * time.Millisecond,
-		WriteTimeout: time.Duration(rc.WriteTimeout) * time.Millisecond,
-		ReadTimeout:  time.Duration(rc.ReadTimeout) * time.Millisecond,
+// validatePool 校验连接池与超时参数
+func (rc *redisConfig) validatePool() error {
+	nonNegative := []struct {
+		key string
+		val int
+	}{
+		{"poolsize", rc.PoolSize},
+		{"min_idle_conns", rc.MinIdleConns},
+		{"dial_timeout", rc.DialTimeout},
+		{"pool_timeout", rc.PoolTimeout},
+		{"conn_max_lifetime", rc.ConnMaxLifetime},
+		{"conn_max_idle_time", rc.ConnMaxIdleTime},
+		{"slow_threshold", rc.SlowThreshold},
+	}
+	for _, item := range nonNegative {
+		if item.val < 0 {
+			return fmt.Errorf("%s 不能为负数: %d", item.key, item.val)
+		}
+	}
+	// read/write_timeout 与 go-redis 语义一致:-1 表示不超时,-2 表示不设置 deadline
+	if rc.ReadTimeout < -2 || rc.WriteTimeout < -2 {
+		return fmt.Errorf("read_timeout/write_timeout 只能为 -1、-2 或非负数")
+	}
+	if rc.MaxRetries < -1 {
+		return fmt.Errorf("max_retries 只能为 -1(不重试)或非负数: %d", rc.MaxRetries)
+	}
+	if rc.PoolSize > 0 && rc.MinIdleConns > rc.PoolSize {
+		return fmt.Errorf("min_idle_conns(%d) 不能大于 poolsize(%d)", rc.MinIdleConns, rc.PoolSize)
+	}
+	return nil
+}
+
+// buildTLSConfig 根据 tls_* 配置构建 TLS 配置,未开启 tls 时返回 nil
+// 证书文件在这里就读取校验,配置错误在启动阶段暴露,而不是首次连接时
+func (rc *redisConfig) buildTLSConfig() (*tls.Config, error) {
+	if !rc.TLS {
+		if rc.TLSCAFile != "" || rc.TLSCertFile != "" || rc.TLSKeyFile != "" || rc.TLSServerName != "" || rc.TLSInsecureSkipVerify {
+			return nil, fmt.Errorf("配置了 tls_* 选项但未开启 tls,请设置 tls = true")
+		}
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         rc.TLSServerName,
+		InsecureSkipVerify: rc.TLSInsecureSkipVerify,
+	}
+	if rc.TLSCAFile != "" {
+		pem, err := os.ReadFile(rc.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 tls_ca_file 失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls_ca_file %s 中没有有效的 PEM 证书", rc.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (rc.TLSCertFile == "") != (rc.TLSKeyFile == "") {
+		return nil, fmt.Errorf("tls_cert_file 和 tls_key_file 必须同时配置")
+	}
+	if rc.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(rc.TLSCertFile, rc.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// ioTimeout 毫秒转为 read/write_timeout;-1、-2 是 go-redis 的特殊值,原样传入不换算
+func ioTimeout(ms int) time.Duration {
+	if ms < 0 {
+		return time.Duration(ms)
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func (rc redisConfig) toOptions() *redis.UniversalOptions {
+	options := &redis.UniversalOptions{
+		Addrs:            rc.Addresses,
+		MasterName:       rc.MasterName,
+		SentinelPassword: rc.SentinelPassword,
+		Password:         rc.Password,
+		DB:               rc.DB,
+		PoolSize:         rc.PoolSize,
+		DialTimeout:      time.Duration(rc.DialTimeout) * time.Millisecond,
+		WriteTimeout:     ioTimeout(rc.WriteTimeout),
+		ReadTimeout:      ioTimeout(rc.ReadTimeout),
+		IsClusterMode:    rc.Mode == ModeCluster,
+		Username:         rc.Username,
+		MinIdleConns:     rc.MinIdleConns,
+		MaxRetries:       rc.MaxRetries,
+		PoolTimeout:      time.Duration(rc.PoolTimeout) * time.Millisecond,
+		ConnMaxLifetime:  time.Duration(rc.ConnMaxLifetime) * time.Millisecond,
+		ConnMaxIdleTime:  time.Duration(rc.ConnMaxIdleTime) * time.Millisecond,
+		TLSConfig:        rc.tlsConfig,
 	}
 	return options
 }
 
 func (rc *redisConfig) newRedis() (*Redis, error) {
 	options := rc.toOptions()
-	client := redis.NewClient(options)
-	return NewRedis(client, options), nil
+	var client redis.UniversalClient
+	// 按 mode 显式创建,不依赖 NewUniversalClient 根据地址个数的推断
+	switch rc.Mode {
+	case ModeSentinel:
+		client = redis.NewFailoverClient(options.Failover())
+	case ModeCluster:
+		client = redis.NewClusterClient(options.Cluster())
+	default:
+		client = redis.NewClient(options.Simple())
+	}
+	r := NewRedis(client, options)
+	r.Mode = rc.Mode
+	r.shareKey = rc.shareKey()
+	// 指标 hook 最先添加、位于最外层:耗时包含其他 hook,慢日志里的 key 是业务传入的原始 key
+	client.AddHook(metricsHook{instance: rc.name, slow: time.Duration(rc.SlowThreshold) * time.Millisecond})
+	if rc.KeyPrefix != "" {
+		r.KeyPrefix = rc.KeyPrefix
+		client.AddHook(keyPrefixHook{prefix: rc.KeyPrefix})
+	}
+	if rc.FailFast {
+		client.AddHook(failFastHook{r: r})
+	}
+	return r, nil
+}
+
+// shareKey 用于判断两个配置能否共用实例:除配置名和 fallback 外的所有配置项。
+// tls_* 按文件路径比较,证书内容在 parse 时已读取
+func (rc redisConfig) shareKey() string {
+	rc.name = ""
+	rc.Fallback = ""
+	return rc.String()
 }
@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRedisConfigParseModes(t *testing.T) {
+	cases := []struct {
+		name      string
+		in        redisConfig
+		wantMode  string
+		wantAddrs []string
+		wantErr   bool
+	}{
+		{"默认单节点", redisConfig{Address: "127.0.0.1"}, ModeStandalone, []string{"127.0.0.1:6379"}, false},
+		{"单节点多地址", redisConfig{Addresses: []string{"a:1", "b:2"}}, "", nil, true},
+		{"单节点缺地址", redisConfig{}, "", nil, true},
+		{"哨兵", redisConfig{Mode: "Sentinel", Addresses: []string{"s1:26379", "s2:26379"}, MasterName: "mymaster"},
+			ModeSentinel, []string{"s1:26379", "s2:26379"}, false},
+		{"哨兵缺 master_name", redisConfig{Mode: "sentinel", Addresses: []string{"s1:26379"}}, "", nil, true},
+		{"集群", redisConfig{Mode: "cluster", Address: "n1:7000", Addresses: []string{" n2:7001 ", ""}},
+			ModeCluster, []string{"n1:7000", "n2:7001"}, false},
+		{"集群不支持 db", redisConfig{Mode: "cluster", Addresses: []string{"n1:7000"}, DB: 1}, "", nil, true},
+		{"未知 mode", redisConfig{Mode: "ring", Address: "a:1"}, "", nil, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var rc redisConfig
+			err := rc.parse(&tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parse err = %v, wantErr=%v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if rc.Mode != tc.wantMode || !slices.Equal(rc.Addresses, tc.wantAddrs) {
+				t.Errorf("mode=%q addrs=%v, want %q %v", rc.Mode, rc.Addresses, tc.wantMode, tc.wantAddrs)
+			}
+		})
+	}
+}
+
+// TestNewRedisByMode 验证按 mode 创建对应类型的客户端
+func TestNewRedisByMode(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	standalone := redisConfig{Mode: ModeStandalone, Addresses: []string{mr.Addr()}}
+	r, _ := standalone.newRedis()
+	defer r.Close()
+	if r.GetClient() == nil || r.Mode != ModeStandalone {
+		t.Errorf("standalone 应为 *redis.Client, mode=%s", r.Mode)
+	}
+	if err := r.Ping(t.Context()).Err(); err != nil {
+		t.Errorf("Ping error: %v", err)
+	}
+
+	cluster := redisConfig{Mode: ModeCluster, Addresses: []string{mr.Addr()}}
+	rc, _ := cluster.newRedis()
+	defer rc.Close()
+	if _, ok := rc.GetUniversalClient().(*redis.ClusterClient); !ok || rc.GetClient() != nil {
+		t.Errorf("cluster 应为 *redis.ClusterClient, got %T", rc.GetUniversalClient())
+	}
+	if rc.Mode != ModeCluster || !rc.Options.IsClusterMode {
+		t.Errorf("cluster mode=%s IsClusterMode=%v", rc.Mode, rc.Options.IsClusterMode)
+	}
+}
+
+// writeSelfSignedCert 生成自签名证书和私钥(PEM)写入临时目录
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "igo-test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	_ = os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	_ = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return certFile, keyFile
+}
+
+func TestRedisConfigParseTLSAndPool(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+	addr := "127.0.0.1:6379"
+	cases := []struct {
+		name    string
+		in      redisConfig
+		wantErr string
+	}{
+		{"tls 完整配置", redisConfig{Address: addr, TLS: true, TLSCAFile: certFile, TLSCertFile: certFile, TLSKeyFile: keyFile}, ""},
+		{"tls_* 未开启 tls", redisConfig{Address: addr, TLSCAFile: certFile}, "tls = true"},
+		{"CA 文件不存在", redisConfig{Address: addr, TLS: true, TLSCAFile: "/nonexistent/ca.pem"}, "tls_ca_file"},
+		{"CA 文件不是证书", redisConfig{Address: addr, TLS: true, TLSCAFile: keyFile}, "没有有效的 PEM 证书"},
+		{"证书缺私钥", redisConfig{Address: addr, TLS: true, TLSCertFile: certFile}, "同时配置"},
+		{"min_idle_conns 超过 poolsize", redisConfig{Address: addr, PoolSize: 5, MinIdleConns: 10}, "min_idle_conns"},
+		{"max_retries 非法", redisConfig{Address: addr, MaxRetries: -2}, "max_retries"},
+		{"负数生命周期", redisConfig{Address: addr, ConnMaxLifetime: -1}, "conn_max_lifetime"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var rc redisConfig
+			err := rc.parse(&tc.in)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("parse error: %v", err)
+				}
+				opts := rc.toOptions()
+				if opts.TLSConfig == nil || opts.TLSConfig.RootCAs == nil || len(opts.TLSConfig.Certificates) != 1 {
+					t.Errorf("TLSConfig 未正确构建: %+v", opts.TLSConfig)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("parse err = %v, want 包含 %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestRedisConfigOptions(t *testing.T) {
+	in := redisConfig{
+		Address: "127.0.0.1:6379", Username: "svc", MinIdleConns: 2, MaxRetries: -1,
+		ConnMaxLifetime: 60000, PoolTimeout: 500,
+	}
+	var rc redisConfig
+	if err := rc.parse(&in); err != nil {
+		t.Fatal(err)
+	}
+	opts := rc.toOptions()
+	if opts.Username != "svc" || opts.MinIdleConns != 2 || opts.MaxRetries != -1 ||
+		opts.ConnMaxLifetime != time.Minute || opts.PoolTimeout != 500*time.Millisecond || opts.TLSConfig != nil {
+		t.Errorf("toOptions = %+v", opts)
+	}
+}
+
+func TestRedisConfigIOTimeoutSpecialValues(t *testing.T) {
+	cases := []struct {
+		read, write         int
+		wantRead, wantWrite time.Duration
+	}{
+		{-1, -2, -1, -2}, // go-redis 特殊值原样传入
+		{0, 0, 0, 0},
+		{3000, 500, 3 * time.Second, 500 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		in := redisConfig{Address: "127.0.0.1:6379", ReadTimeout: tc.read, WriteTimeout: tc.write}
+		var rc redisConfig
+		if err := rc.parse(&in); err != nil {
+			t.Fatal(err)
+		}
+		opts := rc.toOptions()
+		if opts.ReadTimeout != tc.wantRead || opts.WriteTimeout != tc.wantWrite {
+			t.Errorf("read/write_timeout %d/%d => %v/%v, want %v/%v", tc.read, tc.write,
+				opts.ReadTimeout, opts.WriteTimeout, tc.wantRead, tc.wantWrite)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aichy126/igo/log"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultInvalidationChannel 两级缓存默认的失效广播 channel
+const DefaultInvalidationChannel = "igo:cache:invalidate"
+
+// TwoLevel 两级缓存:进程内 LRU(一级)+ Redis(二级)
+// 读先查本地,未命中再读 Redis 并回填本地;Set/Delete 通过 Redis pub/sub 广播失效消息,
+// 所有实例(pod)收到后删除本地副本。广播是尽力而为的,本地 TTL 是脏数据存活时间的上限。
+//
+//	tl, err := cache.NewTwoLevel(rds, cache.WithLocalMaxBytes(32<<20), cache.WithLocalTTL(30*time.Second))
+//	app.AddShutdownHook(tl.Close)
+//	val, err := tl.Get(ctx, "user:1") // 不存在时返回 redis.Nil
+type TwoLevel struct {
+	redis   *Redis
+	local   *localCache
+	ttl     time.Duration
+	channel string
+	id      string // 本实例 ID,忽略自己发出的失效消息
+
+	pubsub *redis.PubSub
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type twoLevelOptions struct {
+	maxBytes int64
+	ttl      time.Duration
+	channel  string
+}
+
+// TwoLevelOption 两级缓存配置项
+type TwoLevelOption func(*twoLevelOptions)
+
+// WithLocalMaxBytes 设置本地缓存容量上限(字节,默认 64MB)
+func WithLocalMaxBytes(n int64) TwoLevelOption {
+	return func(o *twoLevelOptions) {
+		if n > 0 {
+			o.maxBytes = n
+		}
+	}
+}
+
+// WithLocalTTL 设置本地缓存的默认过期时间(默认 1 分钟);
+// 实际 TTL 取它与 Redis 中剩余 TTL 的较小值,本地副本不会比 Redis 活得更久
+func WithLocalTTL(d time.Duration) TwoLevelOption {
+	return func(o *twoLevelOptions) {
+		if d > 0 {
+			o.ttl = d
+		}
+	}
+}
+
+// WithInvalidationChannel 设置失效广播的 pub/sub channel;
+// 实例配置了 key_prefix 时会自动加上该前缀,共用一个 Redis 的不同业务互不干扰
+func WithInvalidationChannel(channel string) TwoLevelOption {
+	return func(o *twoLevelOptions) {
+		if channel != "" {
+			o.channel = channel
+		}
+	}
+}
+
+// invalidation 失效广播消息
+type invalidation struct {
+	ID   string   `json:"id"`
+	Keys []string `json:"keys"`
+}
+
+// NewTwoLevel 在 r 前面叠加一层本地 LRU,并订阅失效广播
+// 订阅失败返回错误;不再使用时调用 Close 停止订阅
+func NewTwoLevel(r *Redis, opts ...TwoLevelOption) (*TwoLevel, error) {
+	if r == nil {
+		return nil, errors.New("两级缓存的 redis 实例不能为 nil")
+	}
+	o := twoLevelOptions{
+		maxBytes: 64 << 20,
+		ttl:      time.Minute,
+		channel:  DefaultInvalidationChannel,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	t := &TwoLevel{
+		redis:   r,
+		local:   newLocalCache(o.maxBytes),
+		ttl:     o.ttl,
+		channel: r.KeyPrefix + o.channel,
+		id:      uuid.New().String(),
+		done:    make(chan struct{}),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.pubsub = r.Subscribe(ctx, t.channel)
+	// 等待订阅确认,连不上尽早暴露
+	pingCtx, pingCancel := context.WithTimeout(ctx, 3*time.Second)
+	_, err := t.pubsub.Receive(pingCtx)
+	pingCancel()
+	if err != nil {
+		cancel()
+		_ = t.pubsub.Close()
+		return nil, fmt.Errorf("订阅两级缓存失效 channel %s 失败: %w", t.channel, err)
+	}
+
+	go t.listen(ctx)
+	return t, nil
+}
+
+// listen 处理失效广播;连接断开重连后无法得知期间错过的消息,直接清空本地缓存
+func (t *TwoLevel) listen(ctx context.Context) {
+	defer close(t.done)
+	ch := t.pubsub.ChannelWithSubscriptions()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			switch m := msg.(type) {
+			case *redis.Subscription:
+				if m.Kind == "subscribe" {
+					t.local.clear()
+					log.Warn("两级缓存失效订阅已重连,清空本地缓存", log.String("channel", t.channel))
+				}
+			case *redis.Message:
+				t.handleInvalidation(m.Payload)
+			}
+		}
+	}
+}
+
+func (t *TwoLevel) handleInvalidation(payload string) {
+	var inv invalidation
+	if err := json.Unmarshal([]byte(payload), &inv); err != nil {
+		log.Warn("两级缓存失效消息解析失败", log.String("payload", payload), log.Any("error", err))
+		return
+	}
+	if inv.ID == t.id {
+		return
+	}
+	t.local.delete(inv.Keys...)
+}
+
+// publish 广播失效消息;失败只记日志,由本地 TTL 兜底
+func (t *TwoLevel) publish(ctx context.Context, keys ...string) {
+	data, _ := json.Marshal(invalidation{ID: t.id, Keys: keys})
+	if err := t.redis.Publish(ctx, t.channel, data).Err(); err != nil {
+		log.Warn("两级缓存失效广播失败", log.Any("keys", keys), log.Any("error", err))
+	}
+}
+
+// Get 读取 key:本地命中直接返回,否则读 Redis 并回填本地;key 不存在时返回 redis.Nil
+func (t *TwoLevel) Get(ctx context.Context, key string) (string, error) {
+	if v, ok := t.local.get(key); ok {
+		return v, nil
+	}
+
+	// GET + PTTL 一次往返,保证本地副本不会比 Redis 中的值活得更久
+	var getCmd *redis.StringCmd
+	var ttlCmd *redis.DurationCmd
+	_, err := t.redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		getCmd = pipe.Get(ctx, key)
+		ttlCmd = pipe.PTTL(ctx, key)
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	val, err := getCmd.Result()
+	if err != nil {
+		return "", err
+	}
+	t.local.set(key, val, t.localTTL(ttlCmd.Val()))
+	return val, nil
+}
+
+// Set 写入 Redis 和本地,并通知其他实例删除旧的本地副本;ttl<=0 表示 Redis 中不过期
+func (t *TwoLevel) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	if err := t.redis.Set(ctx, key, value, ttl).Err(); err != nil {
+		return err
+	}
+	t.local.set(key, value, t.localTTL(ttl))
+	t.publish(ctx, key)
+	return nil
+}
+
+// Delete 删除 Redis 和所有实例本地缓存中的 key
+func (t *TwoLevel) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	t.local.delete(keys...)
+	if err := t.redis.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+	t.publish(ctx, keys...)
+	return nil
+}
+
+// Invalidate 只删除所有实例本地缓存中的 key,不动 Redis
+// 适用于通过脚本等其他途径修改了 Redis 中的值的场景
+func (t *TwoLevel) Invalidate(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	t.local.delete(keys...)
+	t.publish(ctx, keys...)
+}
+
+// localTTL 本地 TTL 取配置值与 Redis 剩余 TTL 的较小值(redisTTL<=0 表示不过期或未知)
+func (t *TwoLevel) localTTL(redisTTL time.Duration) time.Duration {
+	if redisTTL > 0 && redisTTL < t.ttl {
+		return redisTTL
+	}
+	return t.ttl
+}
+
+// LocalStats 返回本地缓存统计
+func (t *TwoLevel) LocalStats() LocalStats {
+	return t.local.stats()
+}
+
+// Close 停止失效订阅并清空本地缓存,可直接注册为关闭钩子
+func (t *TwoLevel) Close() error {
+	t.cancel()
+	err := t.pubsub.Close()
+	<-t.done
+	t.local.clear()
+	return err
+}
@@ -0,0 +1,126 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T, mr *miniredis.Miniredis) *Redis {
+	t.Helper()
+	options := &redis.UniversalOptions{Addrs: []string{mr.Addr()}}
+	r := NewRedis(redis.NewClient(options.Simple()), options)
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+// TestLocalCacheEviction 验证按字节淘汰最久未使用的条目
+func TestLocalCacheEviction(t *testing.T) {
+	lc := newLocalCache(10)
+	lc.set("a", "1111", 0) // 5 字节
+	lc.set("b", "2222", 0) // 5 字节,刚好占满
+	if _, ok := lc.get("a"); !ok {
+		t.Fatal("a 应命中")
+	}
+	lc.set("c", "3333", 0) // 超出容量,淘汰最久未使用的 b
+	if _, ok := lc.get("b"); ok {
+		t.Error("b 应被淘汰")
+	}
+	if _, ok := lc.get("a"); !ok {
+		t.Error("a 刚被访问过,不应被淘汰")
+	}
+	lc.set("big", "0123456789", 0)
+	if _, ok := lc.get("big"); ok {
+		t.Error("超过容量上限的单条值不应缓存")
+	}
+	if st := lc.stats(); st.Bytes > 10 || st.Evictions != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestLocalCacheTTL(t *testing.T) {
+	lc := newLocalCache(1024)
+	lc.set("k", "v", 20*time.Millisecond)
+	if _, ok := lc.get("k"); !ok {
+		t.Fatal("未过期应命中")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if _, ok := lc.get("k"); ok {
+		t.Error("过期后不应命中")
+	}
+}
+
+// TestTwoLevelGetSet 验证本地回填:Redis 中的值被绕过修改后,本地副本仍在 TTL 内生效
+func TestTwoLevelGetSet(t *testing.T) {
+	mr := miniredis.RunT(t)
+	tl, err := NewTwoLevel(newTestRedis(t, mr))
+	if err != nil {
+		t.Fatalf("NewTwoLevel error: %v", err)
+	}
+	defer tl.Close()
+	ctx := t.Context()
+
+	if _, err := tl.Get(ctx, "missing"); !errors.Is(err, redis.Nil) {
+		t.Errorf("不存在的 key 应返回 redis.Nil, got %v", err)
+	}
+
+	mr.Set("k", "v1")
+	if v, err := tl.Get(ctx, "k"); err != nil || v != "v1" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	mr.Set("k", "v2")
+	if v, _ := tl.Get(ctx, "k"); v != "v1" {
+		t.Errorf("本地应命中旧值 v1, got %q", v)
+	}
+
+	if err := tl.Set(ctx, "k", "v3", time.Minute); err != nil {
+		t.Fatalf("Set error: %v", err)
+	}
+	if got, _ := mr.Get("k"); got != "v3" {
+		t.Errorf("Redis 中的值 = %q, want v3", got)
+	}
+	if st := tl.LocalStats(); st.Hits == 0 || st.Entries != 1 {
+		t.Errorf("LocalStats = %+v", st)
+	}
+}
+
+// TestTwoLevelCrossInstanceInvalidation 验证一个实例 Delete 后其他实例的本地副本被清除
+func TestTwoLevelCrossInstanceInvalidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+	podA, err := NewTwoLevel(newTestRedis(t, mr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer podA.Close()
+	podB, err := NewTwoLevel(newTestRedis(t, mr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer podB.Close()
+	ctx := t.Context()
+
+	mr.Set("user:1", "alice")
+	if v, _ := podB.Get(ctx, "user:1"); v != "alice" {
+		t.Fatalf("podB Get = %q", v)
+	}
+	if err := podA.Delete(ctx, "user:1"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := podB.local.get("user:1"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("podB 的本地副本未被失效广播清除")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := podB.Get(ctx, "user:1"); !errors.Is(err, redis.Nil) {
+		t.Errorf("删除后 podB 应读到 redis.Nil, got %v", err)
+	}
+}
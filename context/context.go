@@ -21,6 +21,9 @@ const CommonContextKey = "context"
 const (
 	HeaderGinContextKey = "gin-context"
 	HttpRequestKey      = "Http-Request"
+	// DeadlineContextKey gin.Context 中带截止时间的 context(由 web.Deadline 设置),
+	// NewContextWithGinHeader 以它为父 context
+	DeadlineContextKey = "igo-deadline-context"
 )
 
 type Context = IContext
@@ -85,7 +88,13 @@ func NewContext() IContext {
 }
 
 func NewContextWithGinHeader(c *gin.Context) IContext {
-	ctx := WithContext(context.Background())
+	parent := context.Background()
+	if v, ok := c.Get(DeadlineContextKey); ok {
+		if dctx, ok := v.(context.Context); ok {
+			parent = dctx
+		}
+	}
+	ctx := WithContext(parent)
 	//继承gin header
 	for k, v := range c.Request.Header {
 		ctx.Set(k, v)
@@ -96,6 +105,8 @@ func NewContextWithGinHeader(c *gin.Context) IContext {
 		traceId = uuid.New().String()
 	}
 	ctx.SetMeta("traceId", traceId)
+	//保存原始请求,GetHttpRequest 可取到(如读取 session 等中间件挂在请求 context 上的数据)
+	ctx.Set(HttpRequestKey, c.Request)
 	return ctx
 }
 
@@ -0,0 +1,282 @@
+// Package eventbus 基于 Redis pub/sub 的跨进程事件总线:
+// 事件以 JSON 发布,订阅方按类型自动解码;连接断开后自动重连并重新订阅,
+// 单个 handler panic 不影响其他订阅;总线随传入的 ctx(通常是应用的关闭上下文)一起停止。
+//
+// pub/sub 不持久化,订阅方离线期间的事件会丢失;需要可靠投递请使用 queue 包。
+//
+//	bus := eventbus.New(igo.App.GetShutdownContext(), rds)
+//	eventbus.Subscribe(bus, "user.created", func(ctx context.Context, e UserCreated) error {
+//		return sendWelcome(ctx, e.UserID)
+//	})
+//	bus.Publish(ctx, "user.created", UserCreated{UserID: 1})
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	icontext "github.com/aichy126/igo/context"
+	"github.com/aichy126/igo/log"
+	"github.com/aichy126/igo/util"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultChannelPrefix Redis 频道名默认前缀
+const DefaultChannelPrefix = "igo:eventbus:"
+
+// DefaultBufferSize 每个订阅的待处理事件缓冲,handler 处理不过来且缓冲满时丢弃事件
+const DefaultBufferSize = 100
+
+// ErrClosed 总线已关闭
+var ErrClosed = errors.New("eventbus 已关闭")
+
+// envelope 频道中传输的消息格式
+type envelope struct {
+	TraceID string          `json:"trace_id,omitempty"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type options struct {
+	prefix     string
+	bufferSize int
+}
+
+// Option 总线配置项
+type Option func(*options)
+
+// WithChannelPrefix 设置 Redis 频道名前缀(默认 "igo:eventbus:");
+// 实例配置了 key_prefix 时会再加上 key_prefix
+func WithChannelPrefix(prefix string) Option {
+	return func(o *options) { o.prefix = prefix }
+}
+
+// WithBufferSize 设置每个订阅的事件缓冲大小(默认 100)
+func WithBufferSize(n int) Option {
+	return func(o *options) {
+		if n > 0 {
+			o.bufferSize = n
+		}
+	}
+}
+
+// Bus 事件总线,所有订阅共用一条 pub/sub 连接,并发安全
+type Bus struct {
+	redis  *cache.Redis
+	opts   options
+	ctx    context.Context
+	cancel context.CancelFunc
+	pubsub *redis.PubSub
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	subs map[string][]*Subscription // 频道名 → 订阅
+}
+
+// New 创建事件总线;ctx 取消(如应用开始关闭)时停止接收并结束所有订阅 goroutine
+func New(ctx context.Context, r *cache.Redis, opts ...Option) *Bus {
+	o := options{prefix: DefaultChannelPrefix, bufferSize: DefaultBufferSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.prefix = r.KeyPrefix + o.prefix
+	ctx, cancel := context.WithCancel(ctx)
+	b := &Bus{
+		redis:  r,
+		opts:   o,
+		ctx:    ctx,
+		cancel: cancel,
+		pubsub: r.Subscribe(ctx),
+		subs:   make(map[string][]*Subscription),
+	}
+	b.wg.Add(1)
+	go b.receive()
+	go func() {
+		// 关闭连接才能让阻塞中的 ReceiveMessage 返回
+		<-ctx.Done()
+		_ = b.pubsub.Close()
+	}()
+	return b
+}
+
+// Publish 发布事件,v 序列化为 JSON;ctx 中的 traceId 会透传给订阅方
+func (b *Bus) Publish(ctx context.Context, topic string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("事件 [%s] 序列化失败: %w", topic, err)
+	}
+	env := envelope{Data: data}
+	if ictx, ok := ctx.(icontext.IContext); ok {
+		env.TraceID = ictx.GetString("traceId")
+	}
+	msg, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	return b.redis.Publish(ctx, b.opts.prefix+topic, msg).Err()
+}
+
+// Close 停止总线并等待所有订阅 goroutine 退出(正在执行的 handler 会执行完)
+func (b *Bus) Close() error {
+	b.cancel()
+	b.wg.Wait()
+	return nil
+}
+
+// Subscribe 订阅 topic,事件 JSON 解码为 T 后调用 handler;
+// 同一订阅的事件按顺序处理,handler 返回的错误和 panic 只记录日志
+func Subscribe[T any](b *Bus, topic string, handler func(ctx context.Context, event T) error) (*Subscription, error) {
+	return b.subscribe(topic, func(ctx context.Context, data json.RawMessage) error {
+		var event T
+		if err := json.Unmarshal(data, &event); err != nil {
+			return fmt.Errorf("事件解码失败: %w", err)
+		}
+		return handler(ctx, event)
+	})
+}
+
+func (b *Bus) subscribe(topic string, handle func(ctx context.Context, data json.RawMessage) error) (*Subscription, error) {
+	channel := b.opts.prefix + topic
+	s := &Subscription{
+		bus:     b,
+		topic:   topic,
+		channel: channel,
+		handle:  handle,
+		events:  make(chan *redis.Message, b.opts.bufferSize),
+		done:    make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+	if len(b.subs[channel]) == 0 {
+		if err := b.pubsub.Subscribe(b.ctx, channel); err != nil {
+			return nil, fmt.Errorf("订阅 [%s] 失败: %w", topic, err)
+		}
+	}
+	b.subs[channel] = append(b.subs[channel], s)
+	b.wg.Add(1)
+	go s.run()
+	return s, nil
+}
+
+// receive 从 pub/sub 连接读取消息并分发给订阅;
+// 连接断开时 go-redis 会在下次读取时自动重连并重新订阅所有频道
+func (b *Bus) receive() {
+	defer b.wg.Done()
+	var lastErr time.Time
+	for {
+		msg, err := b.pubsub.ReceiveMessage(b.ctx)
+		if err != nil {
+			if b.ctx.Err() != nil || errors.Is(err, redis.ErrClosed) {
+				return
+			}
+			// 断线期间每次重试都会失败,日志每分钟最多一条
+			if time.Since(lastErr) > time.Minute {
+				log.Warn("eventbus 连接异常,正在重连", log.Any("error", err))
+				lastErr = time.Now()
+			}
+			select {
+			case <-time.After(time.Second):
+			case <-b.ctx.Done():
+				return
+			}
+			continue
+		}
+
+		b.mu.Lock()
+		subs := b.subs[msg.Channel]
+		b.mu.Unlock()
+		for _, s := range subs {
+			select {
+			case s.events <- msg:
+			default:
+				log.Warn("eventbus 订阅处理不过来,丢弃事件", log.String("topic", s.topic), log.Int("buffer", cap(s.events)))
+			}
+		}
+	}
+}
+
+// Subscription 一个订阅
+type Subscription struct {
+	bus       *Bus
+	topic     string
+	channel   string
+	handle    func(ctx context.Context, data json.RawMessage) error
+	events    chan *redis.Message
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Topic 订阅的 topic
+func (s *Subscription) Topic() string {
+	return s.topic
+}
+
+// Unsubscribe 取消订阅,已缓冲未处理的事件会被丢弃
+func (s *Subscription) Unsubscribe() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		b := s.bus
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		subs := b.subs[s.channel]
+		for i, sub := range subs {
+			if sub == s {
+				subs = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+		if len(subs) > 0 {
+			b.subs[s.channel] = subs
+			return
+		}
+		delete(b.subs, s.channel)
+		if b.ctx.Err() == nil {
+			err = b.pubsub.Unsubscribe(b.ctx, s.channel)
+		}
+	})
+	return err
+}
+
+func (s *Subscription) run() {
+	defer s.bus.wg.Done()
+	for {
+		select {
+		case msg := <-s.events:
+			s.dispatch(msg)
+		case <-s.done:
+			return
+		case <-s.bus.ctx.Done():
+			return
+		}
+	}
+}
+
+// dispatch 处理一条消息,handler panic 由 util.Recover 记录后恢复
+func (s *Subscription) dispatch(msg *redis.Message) {
+	defer util.Recover()
+
+	var env envelope
+	if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+		log.Warn("eventbus 消息格式错误", log.String("topic", s.topic), log.Any("error", err))
+		return
+	}
+	if env.TraceID == "" {
+		env.TraceID = uuid.New().String()
+	}
+	ctx := icontext.WithContext(s.bus.ctx)
+	ctx.SetMeta("traceId", env.TraceID)
+
+	if err := s.handle(ctx, env.Data); err != nil {
+		log.Error("eventbus 事件处理失败", log.String("topic", s.topic), log.String("traceId", env.TraceID), log.Any("error", err))
+	}
+}
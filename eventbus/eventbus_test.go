@@ -0,0 +1,170 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	icontext "github.com/aichy126/igo/context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type userCreated struct {
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+func newTestBus(t *testing.T, mr *miniredis.Miniredis, ctx context.Context) *Bus {
+	t.Helper()
+	options := &redis.UniversalOptions{Addrs: []string{mr.Addr()}}
+	r := cache.NewRedis(redis.NewClient(options.Simple()), options)
+	b := New(ctx, r)
+	t.Cleanup(func() {
+		_ = b.Close()
+		_ = r.Close()
+	})
+	return b
+}
+
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(3 * time.Second):
+		t.Fatal("等待事件超时")
+	}
+	var zero T
+	return zero
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	mr := miniredis.RunT(t)
+	pub := newTestBus(t, mr, t.Context())
+	sub := newTestBus(t, mr, t.Context())
+
+	events := make(chan userCreated, 1)
+	traceIDs := make(chan string, 1)
+	if _, err := Subscribe(sub, "user.created", func(ctx context.Context, e userCreated) error {
+		traceIDs <- ctx.(icontext.IContext).GetString("traceId")
+		events <- e
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 第二个订阅 panic 不影响第一个
+	if _, err := Subscribe(sub, "user.created", func(ctx context.Context, e userCreated) error {
+		panic("boom")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := icontext.NewContext()
+	ctx.SetMeta("traceId", "trace-1")
+	for range 2 {
+		if err := pub.Publish(ctx, "user.created", userCreated{UserID: 1, Name: "a"}); err != nil {
+			t.Fatal(err)
+		}
+		if e := receive(t, events); e.UserID != 1 || e.Name != "a" {
+			t.Errorf("event = %+v", e)
+		}
+		if id := receive(t, traceIDs); id != "trace-1" {
+			t.Errorf("traceId = %q, want trace-1", id)
+		}
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	mr := miniredis.RunT(t)
+	b := newTestBus(t, mr, t.Context())
+
+	got := make(chan int, 10)
+	s, err := Subscribe(b, "n", func(ctx context.Context, n int) error {
+		got <- n
+		return errors.New("handler 错误只记录日志")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Publish(t.Context(), "n", 1); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, got)
+
+	if err := s.Unsubscribe(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(mr.PubSubChannels("")) == 0 }, "取消最后一个订阅后应退订频道")
+	_ = b.Publish(t.Context(), "n", 2)
+	select {
+	case n := <-got:
+		t.Errorf("取消订阅后仍收到事件 %d", n)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestResubscribe Redis 重启后自动重连并重新订阅
+func TestResubscribe(t *testing.T) {
+	mr := miniredis.RunT(t)
+	b := newTestBus(t, mr, t.Context())
+
+	got := make(chan string, 10)
+	if _, err := Subscribe(b, "ping", func(ctx context.Context, s string) error {
+		got <- s
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return len(mr.PubSubChannels("")) == 1 }, "订阅未生效")
+
+	mr.Close()
+	time.Sleep(50 * time.Millisecond)
+	if err := mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return len(mr.PubSubChannels("")) == 1 }, "重启后应自动重新订阅")
+	if err := b.Publish(t.Context(), "ping", "after restart"); err != nil {
+		t.Fatal(err)
+	}
+	if s := receive(t, got); s != "after restart" {
+		t.Errorf("got %q", s)
+	}
+}
+
+// TestStopWithContext 传入的 ctx 取消后订阅 goroutine 全部退出
+func TestStopWithContext(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	b := newTestBus(t, mr, ctx)
+	if _, err := Subscribe(b, "x", func(ctx context.Context, v any) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	receive(t, done)
+
+	if _, err := Subscribe(b, "y", func(ctx context.Context, v any) error { return nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("关闭后订阅应返回 ErrClosed, got %v", err)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(msg)
+}
@@ -0,0 +1,224 @@
+package httpclient
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aichy126/igo/log"
+	"github.com/aichy126/igo/metrics"
+)
+
+// 标签 client 为 WithMetrics 指定的客户端名,不用请求 host,避免访问任意 URL 时标签基数无限增长
+var (
+	requestDuration = metrics.NewHistogramVec("igo_httpclient_request_duration_seconds",
+		"httpclient 请求耗时(秒),每次重试单独记录", nil, "client", "class")
+	requestErrors = metrics.NewCounterVec("igo_httpclient_request_errors_total",
+		"httpclient 失败请求数,class 为 4xx/5xx/error(网络错误、超时等)", "client", "class")
+)
+
+// WithMetrics 以 name 为 client 标签上报请求耗时和失败数;未设置时不上报。
+// 按配置创建的客户端(Manager)自动使用配置名
+func WithMetrics(name string) Option {
+	return func(c *Client) { c.metricsName = name }
+}
+
+// 默认脱敏的 header
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+type accessLogOptions struct {
+	level      log.Level
+	sampleRate float64 // 记录 body 的采样比例
+	maxBody    int     // body 截断长度
+	headers    bool
+	redact     map[string]bool
+}
+
+// AccessLogOption 访问日志配置项
+type AccessLogOption func(*accessLogOptions)
+
+// WithLogLevel 访问日志级别(默认 Info);网络错误和 5xx 至少以 Warn 级别记录
+func WithLogLevel(level log.Level) AccessLogOption {
+	return func(o *accessLogOptions) { o.level = level }
+}
+
+// WithBodyLog 按 sampleRate(0~1)采样记录请求/响应 body,超过 maxBytes 截断(<= 0 不截断)
+func WithBodyLog(sampleRate float64, maxBytes int) AccessLogOption {
+	return func(o *accessLogOptions) {
+		o.sampleRate = sampleRate
+		o.maxBody = maxBytes
+	}
+}
+
+// WithHeaderLog 记录请求/响应 header;Authorization、Cookie 等默认脱敏,redact 为额外需要脱敏的 header
+func WithHeaderLog(redact ...string) AccessLogOption {
+	return func(o *accessLogOptions) {
+		o.headers = true
+		for _, h := range redact {
+			o.redact[http.CanonicalHeaderKey(h)] = true
+		}
+	}
+}
+
+// WithAccessLog 开启结构化访问日志:每次请求(含每次重试)记录 method、host、路径模板、状态码、耗时、
+// 第几次尝试、traceId、请求/响应字节数,可选记录 body 和 header
+func WithAccessLog(opts ...AccessLogOption) Option {
+	o := &accessLogOptions{level: log.InfoLevel, redact: make(map[string]bool)}
+	for _, h := range defaultRedactHeaders {
+		o.redact[h] = true
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(c *Client) { c.accessLog = o }
+}
+
+// WithPathTemplate 设置本次请求在访问日志中的路径模板,如 "/users/{id}";
+// 未设置时把路径中的数字、UUID 等 ID 段替换为 {id}
+func WithPathTemplate(template string) ReqOption {
+	return func(ro *requestOptions) { ro.pathTemplate = template }
+}
+
+// exchange 一次请求的记录
+type exchange struct {
+	req      *http.Request
+	reqBody  []byte // 缓冲式请求的 body,流式请求为 nil
+	attempt  int
+	template string
+
+	resp      *http.Response
+	respBody  []byte
+	respBytes int64
+	err       error
+	elapsed   time.Duration
+}
+
+func (x *exchange) finish(start time.Time, resp *http.Response, body []byte, err error) {
+	x.elapsed = time.Since(start)
+	x.resp, x.respBody, x.err = resp, body, err
+	switch {
+	case body != nil:
+		x.respBytes = int64(len(body))
+	case resp != nil:
+		x.respBytes = resp.ContentLength
+	}
+}
+
+// class 状态码分类:2xx/3xx/4xx/5xx,请求失败为 error
+func (x *exchange) class() string {
+	if x.err != nil || x.resp == nil {
+		return "error"
+	}
+	return strconv.Itoa(x.resp.StatusCode/100) + "xx"
+}
+
+// record 上报指标并记录访问日志
+func (c *Client) record(x *exchange) {
+	host, class := x.req.URL.Host, x.class()
+	if c.metricsName != "" {
+		requestDuration.WithLabelValues(c.metricsName, class).Observe(x.elapsed.Seconds())
+		if class == "error" || class == "4xx" || class == "5xx" {
+			requestErrors.WithLabelValues(c.metricsName, class).Inc()
+		}
+	}
+
+	o := c.accessLog
+	if o == nil {
+		return
+	}
+	path := x.template
+	if path == "" {
+		path = pathTemplate(x.req.URL.Path)
+	}
+	fields := []log.Field{
+		log.String("method", x.req.Method),
+		log.String("host", host),
+		log.String("path", path),
+		log.Int("attempt", x.attempt),
+		log.Duration("latency", x.elapsed),
+		log.Int64("req_bytes", max(x.req.ContentLength, 0)),
+	}
+	if traceId := x.req.Header.Get("traceId"); traceId != "" {
+		fields = append(fields, log.String("traceId", traceId))
+	}
+	if x.resp != nil {
+		fields = append(fields, log.Int("status", x.resp.StatusCode), log.Int64("resp_bytes", x.respBytes))
+	}
+	if x.err != nil {
+		fields = append(fields, log.Any("error", x.err))
+	}
+	if o.headers {
+		fields = append(fields, log.Any("req_headers", o.redactHeader(x.req.Header)))
+		if x.resp != nil {
+			fields = append(fields, log.Any("resp_headers", o.redactHeader(x.resp.Header)))
+		}
+	}
+	if o.sampleRate > 0 && rand.Float64() < o.sampleRate {
+		fields = append(fields, log.String("req_body", o.truncateBody(x.reqBody)))
+		if x.respBody != nil {
+			fields = append(fields, log.String("resp_body", o.truncateBody(x.respBody)))
+		}
+	}
+
+	level := o.level
+	if class == "error" || class == "5xx" {
+		level = max(level, log.WarnLevel)
+	}
+	switch level {
+	case log.DebugLevel:
+		log.Debug("httpclient access", fields...)
+	case log.InfoLevel:
+		log.Info("httpclient access", fields...)
+	case log.WarnLevel:
+		log.Warn("httpclient access", fields...)
+	default:
+		log.Error("httpclient access", fields...)
+	}
+}
+
+func (o *accessLogOptions) truncateBody(body []byte) string {
+	if o.maxBody <= 0 {
+		return string(body)
+	}
+	return truncate(string(body), o.maxBody)
+}
+
+// redactHeader 复制 header 并脱敏
+func (o *accessLogOptions) redactHeader(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for k, vs := range h {
+		if o.redact[http.CanonicalHeaderKey(k)] {
+			out[k] = "***"
+		} else {
+			out[k] = strings.Join(vs, ",")
+		}
+	}
+	return out
+}
+
+// pathTemplate 把路径中的 ID 段(纯数字、UUID、长十六进制串)替换为 {id},避免日志聚合时路径发散
+func pathTemplate(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if isIDSegment(seg) {
+			segs[i] = "{id}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+func isIDSegment(seg string) bool {
+	if seg == "" {
+		return false
+	}
+	digits, hex := true, true
+	for _, r := range seg {
+		isDigit := r >= '0' && r <= '9'
+		isHex := isDigit || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F') || r == '-'
+		digits = digits && isDigit
+		hex = hex && isHex
+	}
+	return digits || (hex && len(seg) >= 16)
+}
@@ -0,0 +1,119 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aichy126/igo/context"
+	"github.com/aichy126/igo/log"
+)
+
+// captureLog 把全局日志输出到临时文件,返回读取所有日志行的函数
+func captureLog(t *testing.T) func() []map[string]any {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := log.InitLogger(path, "debug", 10, 1, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	return func() []map[string]any {
+		_ = log.Sync()
+		data, _ := os.ReadFile(path)
+		var entries []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			var m map[string]any
+			if json.Unmarshal([]byte(line), &m) == nil {
+				entries = append(entries, m)
+			}
+		}
+		return entries
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Set-Cookie", "sid=secret")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"igo-response-body"}`))
+	}))
+	defer srv.Close()
+	readLog := captureLog(t)
+	host := mustHost(t, srv.URL)
+
+	c := New(
+		WithAccessLog(WithLogLevel(log.DebugLevel), WithBodyLog(1, 10), WithHeaderLog("X-Secret")),
+		WithRetryPolicy(fastPolicy()),
+		WithMetrics("accesslog_test"),
+	)
+	ctx := context.NewContext()
+	ctx.SetMeta("traceId", "trace-1")
+	_, err := c.Post(ctx, srv.URL+"/users/12345/orders", "application/json", strings.NewReader(`{"a":1}`),
+		WithReqHeader("Authorization", "Bearer token"), WithReqHeader("X-Secret", "s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = c.Get(ctx, srv.URL+"/fail", WithPathTemplate("/fail/{tpl}"))
+
+	var ok, fails []map[string]any
+	for _, e := range readLog() {
+		if e["msg"] != "httpclient access" {
+			continue
+		}
+		if e["status"] == float64(200) {
+			ok = append(ok, e)
+		} else {
+			fails = append(fails, e)
+		}
+	}
+	if len(ok) != 1 || len(fails) != 3 {
+		t.Fatalf("访问日志条数 ok=%d fail=%d", len(ok), len(fails))
+	}
+	e := ok[0]
+	if e["level"] != "DEBUG" || e["method"] != "POST" || e["host"] != host || e["path"] != "/users/{id}/orders" ||
+		e["traceId"] != "trace-1" || e["req_bytes"] != float64(7) || e["resp_bytes"] != float64(28) || e["attempt"] != float64(1) {
+		t.Errorf("访问日志字段不正确: %v", e)
+	}
+	if e["resp_body"] != `{"name":"i...` || e["req_body"] != `{"a":1}` {
+		t.Errorf("body 截断不正确: %v / %v", e["req_body"], e["resp_body"])
+	}
+	reqHeaders, _ := e["req_headers"].(map[string]any)
+	respHeaders, _ := e["resp_headers"].(map[string]any)
+	if reqHeaders["Authorization"] != "***" || reqHeaders["X-Secret"] != "***" || respHeaders["Set-Cookie"] != "***" {
+		t.Errorf("header 未脱敏: %v %v", reqHeaders, respHeaders)
+	}
+
+	// 5xx 至少 Warn,每次重试单独记录
+	for i, f := range fails {
+		if f["level"] != "WARN" || f["path"] != "/fail/{tpl}" || f["attempt"] != float64(i+1) {
+			t.Errorf("失败请求日志不正确: %v", f)
+		}
+	}
+	if v := requestErrors.WithLabelValues("accesslog_test", "5xx").Value(); v != 3 {
+		t.Errorf("5xx 错误数 = %v, want 3", v)
+	}
+	if n := requestDuration.WithLabelValues("accesslog_test", "2xx").Count(); n != 1 {
+		t.Errorf("2xx 耗时记录 %d 次, want 1", n)
+	}
+}
+
+func TestPathTemplate(t *testing.T) {
+	cases := map[string]string{
+		"/users/42": "/users/{id}",
+		"/orders/550e8400-e29b-41d4-a716-446655440000": "/orders/{id}",
+		"/v1/items/abc":              "/v1/items/abc",
+		"/blob/0123456789abcdef0123": "/blob/{id}",
+		"/":                          "/",
+	}
+	for in, want := range cases {
+		if got := pathTemplate(in); got != want {
+			t.Errorf("pathTemplate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
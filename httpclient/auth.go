@@ -0,0 +1,250 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aichy126/igo/log"
+)
+
+// Token 访问令牌
+type Token struct {
+	AccessToken string
+	TokenType   string    // 为空时按 Bearer 处理
+	Expiry      time.Time // 零值表示不过期
+}
+
+// header Authorization 头的值
+func (t *Token) header() string {
+	typ := t.TokenType
+	if typ == "" || strings.EqualFold(typ, "bearer") {
+		typ = "Bearer"
+	}
+	return typ + " " + t.AccessToken
+}
+
+// TokenSource 令牌来源
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc 函数形式的 TokenSource
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) { return f(ctx) }
+
+// StaticTokenSource 固定令牌
+func StaticTokenSource(accessToken string) TokenSource {
+	tok := &Token{AccessToken: accessToken}
+	return TokenSourceFunc(func(context.Context) (*Token, error) { return tok, nil })
+}
+
+// WithBasicAuth 每个请求附加 Basic 认证(请求已带 Authorization 时不覆盖)
+func WithBasicAuth(username, password string) Option {
+	return WithMiddleware(func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") == "" {
+				req.SetBasicAuth(username, password)
+			}
+			return next(req)
+		}
+	})
+}
+
+// WithBearerTokenSource 每个请求附加 ts 提供的令牌(请求已带 Authorization 时不覆盖)。
+// 令牌会被缓存,过期前自动刷新(并发请求只刷新一次);上游返回 401 时强制刷新令牌并重发一次
+func WithBearerTokenSource(ts TokenSource) Option {
+	return WithMiddleware(tokenMiddleware(&cachedTokenSource{src: ts}))
+}
+
+// WithOAuth2ClientCredentials 使用 OAuth2 client credentials 模式从 tokenURL 获取令牌,
+// 缓存与刷新规则同 WithBearerTokenSource。获取令牌的请求复用客户端的 Transport
+func WithOAuth2ClientCredentials(tokenURL, clientID, clientSecret string, scopes []string) Option {
+	return func(c *Client) {
+		src := &clientCredentials{c: c, tokenURL: tokenURL, clientID: clientID, clientSecret: clientSecret, scopes: scopes}
+		WithBearerTokenSource(src)(c)
+	}
+}
+
+// tokenMiddleware 附加令牌,401 时强制刷新并重发一次
+func tokenMiddleware(ts *cachedTokenSource) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "" {
+				return next(req)
+			}
+			tok, err := ts.Token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set("Authorization", tok.header())
+			resp, err := next(req)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			// body 无法重放时不重发
+			retry := req.Clone(req.Context())
+			if req.Body != nil && req.Body != http.NoBody {
+				if req.GetBody == nil {
+					return resp, nil
+				}
+				if retry.Body, err = req.GetBody(); err != nil {
+					return resp, nil
+				}
+			}
+			ts.invalidate(tok)
+			tok, err = ts.Token(req.Context())
+			if err != nil {
+				return resp, nil
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			retry.Header.Set("Authorization", tok.header())
+			return next(retry)
+		}
+	}
+}
+
+// cachedTokenSource 缓存令牌,过期前刷新;并发刷新合并为一次
+type cachedTokenSource struct {
+	src TokenSource
+
+	mu        sync.Mutex
+	tok       *Token
+	refreshAt time.Time // 到达该时间后刷新,零值表示不刷新
+	inflight  *tokenCall
+}
+
+type tokenCall struct {
+	done chan struct{}
+	tok  *Token
+	err  error
+}
+
+func (s *cachedTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	cur := s.tok
+	if cur != nil && (s.refreshAt.IsZero() || time.Now().Before(s.refreshAt)) {
+		s.mu.Unlock()
+		return cur, nil
+	}
+	call := s.inflight
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		s.inflight = call
+		// 刷新不受单个调用方取消的影响,其他等待者共享结果
+		go s.fetch(context.WithoutCancel(ctx), call)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		// 提前刷新失败时,未过期的旧令牌仍可使用
+		if cur != nil && (cur.Expiry.IsZero() || time.Now().Before(cur.Expiry)) {
+			log.Warn("httpclient 刷新令牌失败,继续使用旧令牌", log.Any("error", call.err))
+			return cur, nil
+		}
+		return nil, call.err
+	}
+	return call.tok, nil
+}
+
+func (s *cachedTokenSource) fetch(ctx context.Context, call *tokenCall) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	tok, err := s.src.Token(ctx)
+	if err == nil && (tok == nil || tok.AccessToken == "") {
+		err = fmt.Errorf("令牌为空")
+	}
+
+	s.mu.Lock()
+	if err == nil {
+		s.tok = tok
+		s.refreshAt = time.Time{}
+		if !tok.Expiry.IsZero() {
+			// 提前 1/5 有效期(最多 1 分钟)刷新,避免请求途中过期
+			skew := min(time.Until(tok.Expiry)/5, time.Minute)
+			s.refreshAt = tok.Expiry.Add(-skew)
+		}
+	}
+	s.inflight = nil
+	s.mu.Unlock()
+
+	call.tok, call.err = tok, err
+	close(call.done)
+}
+
+// invalidate 让 tok 失效,下次调用 Token 时重新获取;tok 已被其他请求刷新过时忽略
+func (s *cachedTokenSource) invalidate(tok *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tok == tok {
+		s.tok = nil
+	}
+}
+
+// clientCredentials OAuth2 client credentials 模式的令牌来源
+type clientCredentials struct {
+	c            *Client
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+}
+
+func (cc *clientCredentials) Token(ctx context.Context) (*Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(cc.scopes) > 0 {
+		form.Set("scope", strings.Join(cc.scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cc.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(cc.clientID), url.QueryEscape(cc.clientSecret))
+
+	// 不经过客户端的中间件,避免令牌请求本身又去取令牌
+	hc := &http.Client{Transport: cc.c.hc.Transport, Timeout: cc.c.hc.Timeout}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取 oauth2 令牌失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := readBody(resp.Body, 1<<20)
+	if err != nil {
+		return nil, fmt.Errorf("获取 oauth2 令牌失败: %w", err)
+	}
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = json.Unmarshal(data, &body)
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return nil, fmt.Errorf("获取 oauth2 令牌失败: 状态码 %d, error=%s %s", resp.StatusCode, body.Error, truncate(body.ErrorDescription, 200))
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("获取 oauth2 令牌失败: 响应中没有 access_token: %s", truncate(string(data), 200))
+	}
+	tok := &Token{AccessToken: body.AccessToken, TokenType: body.TokenType}
+	if body.ExpiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
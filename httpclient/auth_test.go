@@ -0,0 +1,151 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenServer OAuth2 令牌接口,每次签发新令牌 token-N
+func newTokenServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32) {
+	var issued atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		_ = r.ParseForm()
+		if !ok || id != "cid" || secret != "csecret" || r.PostForm.Get("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.PostForm.Get("scope") != "read write" {
+			t.Errorf("scope = %q", r.PostForm.Get("scope"))
+		}
+		time.Sleep(20 * time.Millisecond)
+		n := issued.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", n),
+			"token_type":   "bearer",
+			"expires_in":   expiresIn,
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &issued
+}
+
+// newAPIServer 只接受 valid 返回的令牌
+func newAPIServer(t *testing.T, valid func(auth string) bool) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !valid(r.Header.Get("Authorization")) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	tokenSrv, issued := newTokenServer(t, 3600)
+	api, _ := newAPIServer(t, func(auth string) bool { return strings.HasPrefix(auth, "Bearer token-") })
+	c := New(WithOAuth2ClientCredentials(tokenSrv.URL, "cid", "csecret", []string{"read", "write"}))
+
+	// 并发请求只获取一次令牌
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			resp, err := c.Get(context.Background(), api.URL)
+			if err != nil || resp.String() != "Bearer token-1" {
+				t.Errorf("Get = %v, %v", resp, err)
+			}
+		})
+	}
+	wg.Wait()
+	if issued.Load() != 1 {
+		t.Errorf("令牌获取 %d 次, want 1", issued.Load())
+	}
+
+	// 请求自带 Authorization 时不覆盖
+	resp, _ := c.Get(t.Context(), api.URL, WithReqHeader("Authorization", "Bearer token-custom"))
+	if resp.String() != "Bearer token-custom" {
+		t.Errorf("不应覆盖请求自带的 Authorization: %s", resp.String())
+	}
+
+	// 凭证错误
+	bad := New(WithOAuth2ClientCredentials(tokenSrv.URL, "cid", "wrong", nil))
+	if _, err := bad.Get(t.Context(), api.URL); err == nil || !strings.Contains(err.Error(), "invalid_client") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestTokenRefreshBeforeExpiry(t *testing.T) {
+	tokenSrv, issued := newTokenServer(t, 1)
+	api, _ := newAPIServer(t, func(string) bool { return true })
+	c := New(WithOAuth2ClientCredentials(tokenSrv.URL, "cid", "csecret", []string{"read", "write"}))
+
+	resp, _ := c.Get(t.Context(), api.URL)
+	if resp.String() != "Bearer token-1" {
+		t.Fatalf("first = %s", resp.String())
+	}
+	// 有效期 1s,提前 200ms 刷新
+	time.Sleep(850 * time.Millisecond)
+	resp, _ = c.Get(t.Context(), api.URL)
+	if resp.String() != "Bearer token-2" || issued.Load() != 2 {
+		t.Errorf("过期前应刷新令牌: %s, issued=%d", resp.String(), issued.Load())
+	}
+}
+
+func TestTokenRefreshOn401(t *testing.T) {
+	var current atomic.Value
+	current.Store("Bearer token-2")
+	var issued atomic.Int32
+	ts := TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		return &Token{AccessToken: fmt.Sprintf("token-%d", issued.Add(1))}, nil
+	})
+	api, calls := newAPIServer(t, func(auth string) bool { return auth == current.Load() })
+	c := New(WithBearerTokenSource(ts))
+
+	// token-1 被上游拒绝,强制刷新后用 token-2 重发(POST body 可重放)
+	resp, err := c.Post(t.Context(), api.URL, "text/plain", strings.NewReader("x"))
+	if err != nil || resp.StatusCode != http.StatusOK || resp.String() != "Bearer token-2" || calls.Load() != 2 {
+		t.Fatalf("resp=%v err=%v calls=%d", resp, err, calls.Load())
+	}
+
+	// 只重发一次
+	current.Store("nobody")
+	calls.Store(0)
+	resp, _ = c.Get(t.Context(), api.URL)
+	if resp.StatusCode != http.StatusUnauthorized || calls.Load() != 2 {
+		t.Errorf("401 只应重发一次: status=%d calls=%d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestTokenSourceError(t *testing.T) {
+	boom := errors.New("boom")
+	c := New(WithBearerTokenSource(TokenSourceFunc(func(context.Context) (*Token, error) { return nil, boom })))
+	if _, err := c.Get(t.Context(), "http://127.0.0.1:1"); !errors.Is(err, boom) {
+		t.Errorf("err = %v, want boom", err)
+	}
+}
+
+func TestBasicAndStaticAuth(t *testing.T) {
+	api, _ := newAPIServer(t, func(string) bool { return true })
+	resp, _ := New(WithBasicAuth("u", "p")).Get(t.Context(), api.URL)
+	if resp.String() != "Basic dTpw" {
+		t.Errorf("basic = %s", resp.String())
+	}
+	resp, _ = New(WithBearerTokenSource(StaticTokenSource("abc"))).Get(t.Context(), api.URL)
+	if resp.String() != "Bearer abc" {
+		t.Errorf("static = %s", resp.String())
+	}
+}
@@ -0,0 +1,221 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aichy126/igo/log"
+	"github.com/aichy126/igo/metrics"
+)
+
+// LoadBalance 负载均衡策略
+type LoadBalance string
+
+const (
+	RoundRobin   LoadBalance = "round_robin"   // 轮询(默认)
+	LeastPending LoadBalance = "least_pending" // 进行中请求最少的实例优先,适合实例处理能力不均的场景
+)
+
+// 实例摘除默认配置
+const (
+	DefaultEjectFailures = 5
+	DefaultEjectDuration = 30 * time.Second
+)
+
+// ErrNoInstance 服务没有可用实例时返回的错误,可用 errors.Is 判断
+var ErrNoInstance = errors.New("httpclient 没有可用的服务实例")
+
+var instanceEjections = metrics.NewCounterVec("igo_httpclient_instance_ejections_total",
+	"httpclient 因连续失败被摘除的实例次数", "service")
+
+type balancerOptions struct {
+	policy        LoadBalance
+	ejectFailures int
+	ejectDuration time.Duration
+}
+
+// BalancerOption 负载均衡配置项
+type BalancerOption func(*balancerOptions)
+
+// WithLoadBalance 设置负载均衡策略(默认 RoundRobin)
+func WithLoadBalance(policy LoadBalance) BalancerOption {
+	return func(o *balancerOptions) { o.policy = policy }
+}
+
+// WithOutlierEjection 实例连续失败 failures 次(网络错误或 5xx)后摘除 d 时长,到期自动恢复;
+// failures <= 0 关闭摘除(默认连续 5 次摘除 30s)
+func WithOutlierEjection(failures int, d time.Duration) BalancerOption {
+	return func(o *balancerOptions) { o.ejectFailures, o.ejectDuration = failures, d }
+}
+
+// WithResolver 开启服务发现:host 为 svc.<服务名> 的请求(如 http://svc.payment/v1/orders)
+// 每次发送前(含重试)通过 r 解析实例并按策略选择一个,其它请求不受影响。
+// 解析在中间件之后进行,中间件看到的仍是服务地址;https 服务需通过 TLS 配置指定 ServerName
+func WithResolver(r Resolver, opts ...BalancerOption) Option {
+	o := balancerOptions{policy: RoundRobin, ejectFailures: DefaultEjectFailures, ejectDuration: DefaultEjectDuration}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(c *Client) {
+		c.balancer = &balancer{resolver: r, opts: o, services: make(map[string]*serviceState)}
+	}
+}
+
+// balancer 按服务选择实例,并记录实例的进行中请求数和连续失败次数
+type balancer struct {
+	resolver Resolver
+	opts     balancerOptions
+
+	mu       sync.Mutex
+	services map[string]*serviceState
+}
+
+type serviceState struct {
+	next      atomic.Uint64        // 轮询计数
+	instances map[string]*instance // key 为实例地址
+}
+
+type instance struct {
+	addr         string
+	pending      atomic.Int64
+	failures     int       // 连续失败次数,受 balancer.mu 保护
+	ejectedUntil time.Time // 受 balancer.mu 保护
+}
+
+// serviceName 从 host 中取服务名,不是服务地址时返回空
+func serviceName(req *http.Request) string {
+	host := req.URL.Hostname()
+	if !strings.HasPrefix(host, ServiceHostPrefix) {
+		return ""
+	}
+	return strings.TrimPrefix(host, ServiceHostPrefix)
+}
+
+// roundTrip 解析服务地址并发送到选中的实例
+func (b *balancer) roundTrip(next RoundTripFunc) RoundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		service := serviceName(req)
+		if service == "" {
+			return next(req)
+		}
+		addrs, err := b.resolver.Resolve(req.Context(), service)
+		if err != nil {
+			return nil, fmt.Errorf("%w: service=%s: %v", ErrNoInstance, service, err)
+		}
+		inst := b.pick(service, addrs)
+		if inst == nil {
+			return nil, fmt.Errorf("%w: service=%s", ErrNoInstance, service)
+		}
+
+		out := req.Clone(req.Context())
+		out.URL.Host = inst.addr
+		out.Host = ""
+		inst.pending.Add(1)
+		resp, err := next(out)
+		// 调用方取消或超时不计为实例失败
+		failed := err != nil && req.Context().Err() == nil || err == nil && resp.StatusCode >= http.StatusInternalServerError
+		b.report(service, inst, failed)
+		if err != nil {
+			inst.pending.Add(-1)
+			return nil, err
+		}
+		// 响应体读完关闭前仍计为进行中的请求(流式请求可能持续很久)
+		resp.Body = &pendingBody{ReadCloser: resp.Body, inst: inst}
+		return resp, nil
+	}
+}
+
+// pick 按策略从未被摘除的实例中选择一个;全部被摘除时忽略摘除状态,避免摘除导致服务完全不可用
+func (b *balancer) pick(service string, addrs []string) *instance {
+	if len(addrs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	b.mu.Lock()
+	state, ok := b.services[service]
+	if !ok {
+		state = &serviceState{instances: make(map[string]*instance)}
+		b.services[service] = state
+	}
+	candidates := make([]*instance, 0, len(addrs))
+	all := make([]*instance, 0, len(addrs))
+	for _, addr := range addrs {
+		inst, ok := state.instances[addr]
+		if !ok {
+			inst = &instance{addr: addr}
+			state.instances[addr] = inst
+		}
+		all = append(all, inst)
+		if now.After(inst.ejectedUntil) {
+			candidates = append(candidates, inst)
+		}
+	}
+	// 清理已下线的实例
+	if len(state.instances) > len(all) {
+		state.instances = make(map[string]*instance, len(all))
+		for _, inst := range all {
+			state.instances[inst.addr] = inst
+		}
+	}
+	b.mu.Unlock()
+	if len(candidates) == 0 {
+		candidates = all
+	}
+
+	start := int(state.next.Add(1) % uint64(len(candidates)))
+	if b.opts.policy != LeastPending {
+		return candidates[start]
+	}
+	// 从轮询位置开始找进行中请求最少的实例,请求数相同时分散到不同实例
+	best := candidates[start]
+	for i := 1; i < len(candidates); i++ {
+		inst := candidates[(start+i)%len(candidates)]
+		if inst.pending.Load() < best.pending.Load() {
+			best = inst
+		}
+	}
+	return best
+}
+
+// report 记录请求结果,连续失败达到阈值时摘除实例
+func (b *balancer) report(service string, inst *instance, failed bool) {
+	if b.opts.ejectFailures <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		inst.failures = 0
+		return
+	}
+	inst.failures++
+	if inst.failures < b.opts.ejectFailures {
+		return
+	}
+	inst.failures = 0
+	inst.ejectedUntil = time.Now().Add(b.opts.ejectDuration)
+	instanceEjections.WithLabelValues(service).Inc()
+	log.Warn("httpclient 实例连续失败,暂时摘除",
+		log.String("service", service),
+		log.String("addr", inst.addr),
+		log.Duration("duration", b.opts.ejectDuration),
+	)
+}
+
+// pendingBody 关闭时减少实例的进行中请求数
+type pendingBody struct {
+	io.ReadCloser
+	inst *instance
+	once sync.Once
+}
+
+func (p *pendingBody) Close() error {
+	p.once.Do(func() { p.inst.pending.Add(-1) })
+	return p.ReadCloser.Close()
+}
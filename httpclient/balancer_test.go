@@ -0,0 +1,148 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/context"
+)
+
+// newInstance 返回实例 httptest 服务和它的 host:port
+func newInstance(t *testing.T, name string, status *atomic.Int32) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != nil && status.Load() != 0 {
+			w.WriteHeader(int(status.Load()))
+		}
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestResolverRoundRobin(t *testing.T) {
+	a, b := newInstance(t, "a", nil), newInstance(t, "b", nil)
+	c := New(WithResolver(StaticResolver{"payment": {a, b}}))
+
+	got := map[string]int{}
+	for range 10 {
+		resp, err := c.Get(context.Background(), "http://svc.payment/v1/orders")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[resp.String()]++
+	}
+	if got["a"] != 5 || got["b"] != 5 {
+		t.Errorf("轮询分布 = %v, want 各 5 次", got)
+	}
+
+	// 非服务地址不经过解析
+	direct, err := c.Get(context.Background(), "http://"+a)
+	if err != nil || direct.String() != "a" {
+		t.Errorf("直连请求 = %v, %v", direct, err)
+	}
+	// 未知服务
+	if _, err := c.Get(context.Background(), "http://svc.unknown/"); !errors.Is(err, ErrNoInstance) {
+		t.Errorf("未知服务 error = %v, want ErrNoInstance", err)
+	}
+}
+
+func TestResolverLeastPending(t *testing.T) {
+	b := &balancer{opts: balancerOptions{policy: LeastPending}, services: make(map[string]*serviceState)}
+	addrs := []string{"a:1", "b:1", "c:1"}
+	busy := b.pick("svc", addrs)
+	busy.pending.Add(10)
+	for range 6 {
+		if inst := b.pick("svc", addrs); inst == busy {
+			t.Fatalf("不应选择进行中请求最多的实例 %s", busy.addr)
+		}
+	}
+	// 实例下线后清理状态
+	b.pick("svc", addrs[:1])
+	if n := len(b.services["svc"].instances); n != 1 {
+		t.Errorf("下线实例未清理,剩余 %d 个", n)
+	}
+}
+
+func TestResolverOutlierEjection(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusBadGateway)
+	bad, good := newInstance(t, "bad", &status), newInstance(t, "good", nil)
+	c := New(WithResolver(StaticResolver{"payment": {bad, good}}, WithOutlierEjection(2, time.Minute)))
+
+	got := map[string]int{}
+	for range 10 {
+		resp, err := c.Get(context.Background(), "http://svc.payment/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[resp.String()]++
+	}
+	// 前 4 次轮询中 bad 失败 2 次后被摘除,之后全部发往 good
+	if got["bad"] != 2 || got["good"] != 8 {
+		t.Errorf("摘除后分布 = %v, want bad 2 次", got)
+	}
+}
+
+func TestResolverAllEjected(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	only := newInstance(t, "only", &status)
+	c := New(WithResolver(StaticResolver{"payment": {only}}, WithOutlierEjection(1, time.Minute)))
+	for range 3 {
+		// 全部被摘除时仍然放行,避免服务完全不可用
+		if resp, err := c.Get(context.Background(), "http://svc.payment/"); err != nil || resp.String() != "only" {
+			t.Fatalf("resp = %v, err = %v", resp, err)
+		}
+	}
+}
+
+func TestConsulResolver(t *testing.T) {
+	var calls, fail atomic.Int32
+	consul := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if fail.Load() == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path != "/v1/health/service/payment" || r.URL.Query().Get("passing") != "1" || r.URL.Query().Get("tag") != "v2" {
+			t.Errorf("consul 请求 = %s", r.URL)
+		}
+		json.NewEncoder(w).Encode([]map[string]any{
+			{"Node": map[string]any{"Address": "10.0.0.1"}, "Service": map[string]any{"Address": "", "Port": 8080}},
+			{"Node": map[string]any{"Address": "10.0.0.2"}, "Service": map[string]any{"Address": "10.0.1.2", "Port": 9090}},
+		})
+	}))
+	defer consul.Close()
+
+	r, err := NewConsulResolver(strings.TrimPrefix(consul.URL, "http://"), WithConsulTag("v2"), WithConsulRefresh(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrs, err := r.Resolve(context.Background(), "payment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(addrs, ",") != "10.0.0.1:8080,10.0.1.2:9090" {
+		t.Errorf("addrs = %v", addrs)
+	}
+
+	// consul 故障时继续使用旧结果
+	fail.Store(1)
+	time.Sleep(2 * time.Millisecond)
+	if addrs, err = r.Resolve(context.Background(), "payment"); err != nil || len(addrs) != 2 {
+		t.Errorf("consul 故障时 addrs = %v, err = %v", addrs, err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("consul 请求次数 = %d, want 2", calls.Load())
+	}
+	if _, err := r.Resolve(context.Background(), "other"); err == nil {
+		t.Error("没有缓存时 consul 故障应返回错误")
+	}
+}
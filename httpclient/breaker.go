@@ -0,0 +1,286 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aichy126/igo/log"
+	"github.com/aichy126/igo/metrics"
+)
+
+// CircuitState 熔断器状态
+type CircuitState int
+
+const (
+	StateClosed   CircuitState = iota // 关闭:请求正常放行
+	StateOpen                         // 打开:请求直接失败
+	StateHalfOpen                     // 半开:放行少量探测请求
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// ErrCircuitOpen 熔断器打开时请求返回的错误,可用 errors.Is 判断
+var ErrCircuitOpen = errors.New("httpclient 熔断器已打开")
+
+// CircuitOpenError 熔断器打开时返回的错误,RetryAfter 为距离进入半开状态的剩余时间
+type CircuitOpenError struct {
+	Host       string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("httpclient 熔断器已打开: host=%s, %v 后重试", e.Host, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+var (
+	circuitState = metrics.NewGaugeVec("igo_httpclient_circuit_state",
+		"httpclient 熔断器状态:0 关闭,1 打开,2 半开", "host")
+	circuitTransitions = metrics.NewCounterVec("igo_httpclient_circuit_transitions_total",
+		"httpclient 熔断器状态切换次数", "host", "state")
+	circuitRejected = metrics.NewCounterVec("igo_httpclient_circuit_rejected_total",
+		"httpclient 熔断器打开时被拒绝的请求数", "host")
+)
+
+// 熔断器默认配置
+const (
+	DefaultFailureRatio     = 0.5
+	DefaultMinRequests      = 20
+	DefaultBreakerWindow    = 10 * time.Second
+	DefaultOpenTimeout      = 30 * time.Second
+	DefaultHalfOpenRequests = 1
+)
+
+// FailureFunc 判断一次请求是否计为失败
+type FailureFunc func(resp *Response, err error) bool
+
+// DefaultFailure 网络层错误和 5xx 计为失败,4xx 和响应体超限属于调用方问题不计入
+func DefaultFailure(resp *Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, ErrResponseTooLarge)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
+
+type breakerOptions struct {
+	ratio       float64
+	minRequests int
+	window      time.Duration
+	openTimeout time.Duration
+	halfOpen    int
+	isFailure   FailureFunc
+}
+
+// BreakerOption 熔断器配置项
+type BreakerOption func(*breakerOptions)
+
+// WithFailureRatio 窗口内失败率达到 ratio 时打开熔断器(默认 0.5)
+func WithFailureRatio(ratio float64) BreakerOption {
+	return func(o *breakerOptions) { o.ratio = ratio }
+}
+
+// WithMinRequests 窗口内请求数达到 n 才计算失败率,避免少量请求误判(默认 20)
+func WithMinRequests(n int) BreakerOption {
+	return func(o *breakerOptions) { o.minRequests = n }
+}
+
+// WithBreakerWindow 统计窗口(默认 10s),关闭状态下每个窗口重新计数
+func WithBreakerWindow(d time.Duration) BreakerOption {
+	return func(o *breakerOptions) { o.window = d }
+}
+
+// WithOpenTimeout 打开状态持续多久后进入半开状态(默认 30s)
+func WithOpenTimeout(d time.Duration) BreakerOption {
+	return func(o *breakerOptions) { o.openTimeout = d }
+}
+
+// WithHalfOpenRequests 半开状态放行的探测请求数,全部成功后关闭熔断器(默认 1)
+func WithHalfOpenRequests(n int) BreakerOption {
+	return func(o *breakerOptions) { o.halfOpen = n }
+}
+
+// WithFailureFunc 自定义失败判定(默认 DefaultFailure)
+func WithFailureFunc(f FailureFunc) BreakerOption {
+	return func(o *breakerOptions) { o.isFailure = f }
+}
+
+// WithCircuitBreaker 按上游 host 开启熔断:失败率超过阈值后打开,打开期间请求直接返回 *CircuitOpenError,
+// 超时后进入半开状态放行探测请求,探测成功则恢复。状态切换记 Warn 日志并上报指标
+func WithCircuitBreaker(opts ...BreakerOption) Option {
+	o := breakerOptions{
+		ratio:       DefaultFailureRatio,
+		minRequests: DefaultMinRequests,
+		window:      DefaultBreakerWindow,
+		openTimeout: DefaultOpenTimeout,
+		halfOpen:    DefaultHalfOpenRequests,
+		isFailure:   DefaultFailure,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.minRequests < 1 {
+		o.minRequests = 1
+	}
+	if o.halfOpen < 1 {
+		o.halfOpen = 1
+	}
+	return func(c *Client) {
+		c.breakers = &breakerGroup{o: o, hosts: make(map[string]*breaker)}
+	}
+}
+
+// breakerGroup 按 host 管理熔断器
+type breakerGroup struct {
+	o     breakerOptions
+	mu    sync.Mutex
+	hosts map[string]*breaker
+}
+
+func (g *breakerGroup) get(host string) *breaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	b, ok := g.hosts[host]
+	if !ok {
+		b = &breaker{host: host, o: &g.o, windowStart: time.Now()}
+		g.hosts[host] = b
+	}
+	return b
+}
+
+// state 返回 host 当前的熔断状态(打开已超时的视为半开)
+func (g *breakerGroup) state(host string) CircuitState {
+	b := g.get(host)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.advance(time.Now())
+	return b.state
+}
+
+// 请求结果
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnore // 调用方取消等,不计入统计
+)
+
+// breaker 单个 host 的熔断器
+type breaker struct {
+	host string
+	o    *breakerOptions
+
+	mu          sync.Mutex
+	state       CircuitState
+	generation  uint64 // 每次状态切换或窗口重置加 1,丢弃旧周期请求的结果
+	windowStart time.Time
+	openedAt    time.Time
+	requests    int
+	failures    int
+	inFlight    int // 半开状态下进行中的探测请求
+	successes   int // 半开状态下成功的探测请求
+}
+
+// allow 判断请求能否放行,返回本次请求所属的周期
+func (b *breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.advance(now)
+	switch b.state {
+	case StateOpen:
+		circuitRejected.WithLabelValues(b.host).Inc()
+		return 0, &CircuitOpenError{Host: b.host, RetryAfter: b.o.openTimeout - now.Sub(b.openedAt)}
+	case StateHalfOpen:
+		if b.inFlight+b.successes >= b.o.halfOpen {
+			circuitRejected.WithLabelValues(b.host).Inc()
+			return 0, &CircuitOpenError{Host: b.host}
+		}
+		b.inFlight++
+	default:
+		b.requests++
+	}
+	return b.generation, nil
+}
+
+// done 记录请求结果
+func (b *breaker) done(generation uint64, result outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	b.advance(now)
+	if generation != b.generation {
+		return
+	}
+	switch b.state {
+	case StateHalfOpen:
+		b.inFlight--
+		switch result {
+		case outcomeFailure:
+			b.setState(StateOpen, now)
+		case outcomeSuccess:
+			if b.successes++; b.successes >= b.o.halfOpen {
+				b.setState(StateClosed, now)
+			}
+		}
+	case StateClosed:
+		switch result {
+		case outcomeFailure:
+			b.failures++
+			if b.requests >= b.o.minRequests && float64(b.failures)/float64(b.requests) >= b.o.ratio {
+				b.setState(StateOpen, now)
+			}
+		case outcomeIgnore:
+			b.requests--
+		}
+	}
+}
+
+// advance 处理随时间发生的变化:关闭状态的窗口重置、打开超时进入半开
+func (b *breaker) advance(now time.Time) {
+	switch b.state {
+	case StateClosed:
+		if now.Sub(b.windowStart) >= b.o.window {
+			b.generation++
+			b.windowStart = now
+			b.requests, b.failures = 0, 0
+		}
+	case StateOpen:
+		if now.Sub(b.openedAt) >= b.o.openTimeout {
+			b.setState(StateHalfOpen, now)
+		}
+	}
+}
+
+func (b *breaker) setState(state CircuitState, now time.Time) {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.requests, b.failures, b.inFlight, b.successes = 0, 0, 0, 0
+	b.windowStart = now
+	if state == StateOpen {
+		b.openedAt = now
+	}
+	circuitState.WithLabelValues(b.host).Set(float64(state))
+	circuitTransitions.WithLabelValues(b.host, state.String()).Inc()
+	log.Warn("httpclient 熔断器状态变化",
+		log.String("host", b.host),
+		log.String("from", from.String()),
+		log.String("to", state.String()),
+	)
+}
@@ -0,0 +1,124 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	host := mustHost(t, srv.URL)
+
+	c := New(WithCircuitBreaker(
+		WithMinRequests(4),
+		WithFailureRatio(0.5),
+		WithOpenTimeout(100*time.Millisecond),
+	))
+	for range 4 {
+		resp, err := c.Get(t.Context(), srv.URL)
+		if err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("打开前应正常返回上游响应: %v %v", resp, err)
+		}
+	}
+	if s := c.CircuitState(host); s != StateOpen {
+		t.Fatalf("state = %v, want open", s)
+	}
+
+	// 打开期间直接失败,不访问上游
+	_, err := c.Get(t.Context(), srv.URL)
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) || openErr.Host != host {
+		t.Fatalf("err = %v, want *CircuitOpenError", err)
+	}
+	if calls.Load() != 4 {
+		t.Errorf("打开期间不应访问上游, calls = %d", calls.Load())
+	}
+	if v := circuitState.WithLabelValues(host).Value(); v != float64(StateOpen) {
+		t.Errorf("状态指标 = %v", v)
+	}
+
+	// 半开探测失败,重新打开
+	time.Sleep(120 * time.Millisecond)
+	if s := c.CircuitState(host); s != StateHalfOpen {
+		t.Fatalf("state = %v, want half-open", s)
+	}
+	_, _ = c.Get(t.Context(), srv.URL)
+	if s := c.CircuitState(host); s != StateOpen {
+		t.Fatalf("探测失败后 state = %v, want open", s)
+	}
+
+	// 半开探测成功,关闭
+	healthy.Store(true)
+	time.Sleep(120 * time.Millisecond)
+	if _, err := c.Get(t.Context(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if s := c.CircuitState(host); s != StateClosed {
+		t.Fatalf("探测成功后 state = %v, want closed", s)
+	}
+}
+
+func TestCircuitBreakerPerHost(t *testing.T) {
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound) // 4xx 不计为失败
+	}))
+	defer good.Close()
+
+	c := New(WithCircuitBreaker(WithMinRequests(2)))
+	for range 3 {
+		_, _ = c.Get(t.Context(), bad.URL)
+		_, _ = c.Get(t.Context(), good.URL)
+	}
+	if s := c.CircuitState(mustHost(t, bad.URL)); s != StateOpen {
+		t.Errorf("bad state = %v, want open", s)
+	}
+	if s := c.CircuitState(mustHost(t, good.URL)); s != StateClosed {
+		t.Errorf("good state = %v, want closed", s)
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	// 失败分散在不同窗口,单个窗口内请求数达不到 min_requests,不打开
+	c := New(WithCircuitBreaker(WithMinRequests(3), WithBreakerWindow(50*time.Millisecond)))
+	for range 4 {
+		_, _ = c.Get(t.Context(), srv.URL)
+		_, _ = c.Get(t.Context(), srv.URL)
+		time.Sleep(60 * time.Millisecond)
+	}
+	if s := c.CircuitState(mustHost(t, srv.URL)); s != StateClosed {
+		t.Errorf("state = %v, want closed", s)
+	}
+}
+
+func mustHost(t *testing.T, rawurl string) string {
+	t.Helper()
+	u, err := neturl.Parse(rawurl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Host
+}
@@ -17,6 +17,7 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -36,9 +37,20 @@ type headerCarrier interface {
 // Client HTTP 客户端,并发安全,建议复用(内部连接池)
 type Client struct {
 	hc      *http.Client
+	baseURL string      // 相对路径请求的前缀
 	headers http.Header // 每个请求都会附带的默认 header(如 User-Agent)
-	retries int         // 网络层错误的重试次数(HTTP 状态码错误不重试)
+	retry   RetryPolicy // 重试策略,默认不重试
 	debug   bool        // 打印请求/响应日志(Debug 级别)
+
+	maxResponseSize int64         // 缓冲式 API 的响应体上限(字节),0 不限制
+	breakers        *breakerGroup // 按 host 熔断,nil 表示未开启
+	middlewares     []Middleware
+	accessLog       *accessLogOptions // 访问日志,nil 表示未开启
+	metricsName     string            // 指标的 client 标签,为空不上报请求指标
+	tls             *tlsFiles         // 从文件加载的 CA/客户端证书,nil 表示未配置
+	balancer        *balancer         // 服务发现与负载均衡,nil 表示未开启
+	hedge           HedgePolicy       // 对冲策略,默认不对冲
+	noDeadline      bool              // 不透传剩余超时(DeadlineHeader)
 }
 
 // Option 客户端配置项
@@ -49,6 +61,22 @@ func WithTimeout(d time.Duration) Option {
 	return func(c *Client) { c.hc.Timeout = d }
 }
 
+// WithBaseURL 设置基础地址,之后可以只传路径:client.GetJSON(ctx, "/v1/orders", &out)
+// 传入完整 URL(带 scheme)的请求不受影响
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) { c.baseURL = strings.TrimRight(baseURL, "/") }
+}
+
+// WithTLSConfig 设置 TLS 配置(完全替换;与 WithMinTLSVersion/WithInsecureSkipVerify 同用时请放在它们之前)。
+// 需要证书热重载时使用 WithRootCAs/WithClientCertificate
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(c *Client) {
+		if t, ok := c.hc.Transport.(*http.Transport); ok {
+			t.TLSClientConfig = cfg
+		}
+	}
+}
+
 // WithUserAgent 设置 User-Agent
 func WithUserAgent(ua string) Option {
 	return func(c *Client) { c.headers.Set("User-Agent", ua) }
@@ -59,11 +87,12 @@ func WithHeader(key, value string) Option {
 	return func(c *Client) { c.headers.Set(key, value) }
 }
 
-// WithRetries 设置网络层错误(连接失败、超时等)的重试次数,HTTP 状态码错误不重试
+// WithRetries 设置网络层错误(连接失败、超时等)的重试次数,HTTP 状态码错误不重试,所有请求方法都会重试。
+// 需要按状态码重试、区分幂等性时请使用 WithRetryPolicy
 func WithRetries(n int) Option {
 	return func(c *Client) {
 		if n > 0 {
-			c.retries = n
+			c.retry = RetryPolicy{MaxRetries: n, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second, RetryNonIdempotent: true}
 		}
 	}
 }
@@ -105,6 +134,12 @@ func WithInsecureSkipVerify() Option {
 	}
 }
 
+// WithMaxResponseSize 限制缓冲式 API(Do/Get/GetJSON 等)读入内存的响应体大小,超过时返回 ErrResponseTooLarge;
+// 不影响 Stream/Download
+func WithMaxResponseSize(n int64) Option {
+	return func(c *Client) { c.maxResponseSize = n }
+}
+
 // WithDebug 开启请求/响应日志
 func WithDebug(b bool) Option {
 	return func(c *Client) { c.debug = b }
@@ -122,6 +157,9 @@ func New(opts ...Option) *Client {
 	for _, opt := range opts {
 		opt(c)
 	}
+	if c.tls != nil {
+		c.tls.install(c)
+	}
 	return c
 }
 
@@ -145,6 +183,11 @@ type Response struct {
 	StatusCode int
 	Header     http.Header
 	Body       []byte
+
+	// 生成 *Error 用的请求信息
+	method   string
+	url      string
+	attempts int
 }
 
 // OK 状态码是否为 2xx
@@ -163,8 +206,10 @@ func (r *Response) JSON(v any) error {
 }
 
 // Do 发起请求并读取完整响应。body 会被完整缓冲以支持重试。
-// header 参数为本次请求的附加 header,可为 nil。
-func (c *Client) Do(ctx context.Context, method, rawurl string, body io.Reader, header http.Header) (*Response, error) {
+// header 参数为本次请求的附加 header,可为 nil;opts 在 header 之后生效。
+func (c *Client) Do(ctx context.Context, method, rawurl string, body io.Reader, header http.Header, opts ...ReqOption) (*Response, error) {
+	ro := newRequestOptions(header, opts)
+
 	// 缓冲 body:支持重试重放,也便于 debug 输出
 	var bodyBytes []byte
 	if body != nil {
@@ -175,61 +220,150 @@ func (c *Client) Do(ctx context.Context, method, rawurl string, body io.Reader,
 		}
 	}
 
-	var lastErr error
-	attempts := c.retries + 1
-	for i := 0; i < attempts; i++ {
-		if i > 0 {
-			select {
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			case <-time.After(time.Duration(i) * 100 * time.Millisecond):
-			}
+	rawurl = c.resolveURL(rawurl)
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	header = c.mergeHeader(ctx, ro.header)
+	policy := c.retry
+	if ro.retry != nil {
+		policy = *ro.retry
+	}
+	retryable := policy.canRetry(method, header)
+	hedge := c.hedge
+	if ro.hedge != nil {
+		hedge = *ro.hedge
+	}
+	hedging := hedge.enabled(method, header, policy.IdempotencyHeader)
+
+	for i := 0; ; i++ {
+		var resp *Response
+		var err error
+		if hedging {
+			resp, err = c.hedged(ctx, u.Host, method, rawurl, bodyBytes, header, ro, i+1, hedge, policy)
+		} else {
+			resp, err = c.attempt(ctx, u.Host, method, rawurl, bodyBytes, header, ro, i+1)
+		}
+		// ctx 取消/超时、熔断器打开、响应体超限不重试
+		if i >= policy.MaxRetries || !retryable || ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrResponseTooLarge) {
+			return resp, newRequestError(method, rawurl, i+1, err)
 		}
-		resp, err := c.doOnce(ctx, method, rawurl, bodyBytes, header)
-		if err == nil {
+		var delay time.Duration
+		switch {
+		case err != nil:
+			delay = policy.backoff(i + 1)
+		case policy.retryStatus(resp.StatusCode):
+			delay = policy.backoff(i + 1)
+			if d, ok := retryAfter(resp.Header, time.Now()); ok {
+				// 上游要求的等待时间超过上限,直接返回响应由调用方处理
+				if policy.MaxDelay > 0 && d > policy.MaxDelay {
+					return resp, nil
+				}
+				delay = d
+			}
+		default:
 			return resp, nil
 		}
-		lastErr = err
-		// ctx 取消/超时不重试
-		if ctx.Err() != nil {
-			break
+		if c.debug {
+			log.Debug("httpclient 重试", log.String("method", method), log.String("url", rawurl),
+				log.Int("attempt", i+1), log.Duration("delay", delay), log.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, newRequestError(method, rawurl, i+1, ctx.Err())
+		case <-time.After(delay):
 		}
 	}
-	return nil, lastErr
 }
 
-func (c *Client) doOnce(ctx context.Context, method, rawurl string, body []byte, header http.Header) (*Response, error) {
-	var reader io.Reader
-	if body != nil {
-		reader = bytes.NewReader(body)
-	}
-	req, err := http.NewRequestWithContext(ctx, method, rawurl, reader)
-	if err != nil {
-		return nil, err
+// resolveURL 相对路径拼接 baseURL
+func (c *Client) resolveURL(rawurl string) string {
+	if c.baseURL == "" || strings.Contains(rawurl, "://") {
+		return rawurl
 	}
+	return c.baseURL + "/" + strings.TrimLeft(rawurl, "/")
+}
 
-	// 默认 header → igo context meta 透传(含 traceId) → 本次请求 header,后者覆盖前者
+// mergeHeader 合并 header:默认 header → igo context meta 透传(含 traceId) → 本次请求 header,后者覆盖前者
+func (c *Client) mergeHeader(ctx context.Context, header http.Header) http.Header {
+	merged := http.Header{}
 	for k, vs := range c.headers {
 		for _, v := range vs {
-			req.Header.Set(k, v)
+			merged.Set(k, v)
 		}
 	}
 	if hc, ok := ctx.(headerCarrier); ok {
 		for k, vs := range hc.GetHeaders() {
 			for _, v := range vs {
-				req.Header.Set(k, v)
+				merged.Set(k, v)
 			}
 		}
 	}
 	for k, vs := range header {
 		for _, v := range vs {
-			req.Header.Set(k, v)
+			merged.Set(k, v)
 		}
 	}
+	return merged
+}
 
+// attempt 发起一次请求;开启熔断时先经过 host 对应的熔断器
+func (c *Client) attempt(ctx context.Context, host, method, rawurl string, body []byte, header http.Header, ro *requestOptions, n int) (*Response, error) {
+	return c.guard(ctx, host, func() (*Response, error) {
+		return c.doOnce(ctx, method, rawurl, body, header, ro, n)
+	})
+}
+
+// guard 经过 host 对应的熔断器执行 fn,未开启熔断时直接执行
+func (c *Client) guard(ctx context.Context, host string, fn func() (*Response, error)) (*Response, error) {
+	if c.breakers == nil {
+		return fn()
+	}
+	b := c.breakers.get(host)
+	generation, err := b.allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := fn()
+	switch {
+	case ctx.Err() != nil:
+		b.done(generation, outcomeIgnore)
+	case c.breakers.o.isFailure(resp, err):
+		b.done(generation, outcomeFailure)
+	default:
+		b.done(generation, outcomeSuccess)
+	}
+	return resp, err
+}
+
+// CircuitState 返回上游 host(host:port 形式,与请求 URL 一致)当前的熔断状态,未开启熔断时恒为关闭
+func (c *Client) CircuitState(host string) CircuitState {
+	if c.breakers == nil {
+		return StateClosed
+	}
+	return c.breakers.state(host)
+}
+
+// doOnce 发起一次请求并读取完整响应,n 为第几次尝试(从 1 开始)
+func (c *Client) doOnce(ctx context.Context, method, rawurl string, body []byte, header http.Header, ro *requestOptions, n int) (*Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawurl, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header.Clone()
+	c.setDeadline(req, c.hc.Timeout)
+
+	x := &exchange{req: req, reqBody: body, attempt: n, template: ro.pathTemplate}
 	start := time.Now()
-	resp, err := c.hc.Do(req)
+	resp, err := c.send(c.hc, req)
 	if err != nil {
+		x.finish(start, nil, nil, err)
+		c.record(x)
 		if c.debug {
 			log.Error("httpclient 请求失败", log.String("method", method), log.String("url", rawurl), log.Any("error", err))
 		}
@@ -237,9 +371,11 @@ func (c *Client) doOnce(ctx context.Context, method, rawurl string, body []byte,
 	}
 	defer resp.Body.Close()
 
-	data, err := io.ReadAll(resp.Body)
+	data, err := readBody(resp.Body, c.maxResponseSize)
+	x.finish(start, resp, data, err)
+	c.record(x)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应 body 失败: %w", err)
+		return nil, err
 	}
 
 	if c.debug {
@@ -247,7 +383,7 @@ func (c *Client) doOnce(ctx context.Context, method, rawurl string, body []byte,
 			log.String("method", method),
 			log.String("url", rawurl),
 			log.Int("status", resp.StatusCode),
-			log.Duration("cost", time.Since(start)),
+			log.Duration("cost", x.elapsed),
 			log.String("request", string(body)),
 			log.String("response", string(data)),
 		)
@@ -257,60 +393,96 @@ func (c *Client) doOnce(ctx context.Context, method, rawurl string, body []byte,
 		StatusCode: resp.StatusCode,
 		Header:     resp.Header,
 		Body:       data,
+		method:     method,
+		url:        rawurl,
+		attempts:   n,
 	}, nil
 }
 
-// ReqOption 单次请求的配置项(目前用于附加 header)
-type ReqOption func(http.Header)
+// requestOptions 单次请求的配置
+type requestOptions struct {
+	header       http.Header
+	retry        *RetryPolicy // 覆盖客户端的重试策略
+	hedge        *HedgePolicy // 覆盖客户端的对冲策略
+	pathTemplate string       // 访问日志中的路径模板
+}
+
+func newRequestOptions(header http.Header, opts []ReqOption) *requestOptions {
+	ro := &requestOptions{header: http.Header{}}
+	for k, vs := range header {
+		for _, v := range vs {
+			ro.header.Add(k, v)
+		}
+	}
+	for _, opt := range opts {
+		opt(ro)
+	}
+	return ro
+}
+
+// ReqOption 单次请求的配置项。
+// 注意:v0.6.0 起为破坏性变更,此前的类型为 func(http.Header);自定义的旧式选项用 ReqHeaderFunc 包装
+type ReqOption func(*requestOptions)
+
+// ReqHeaderFunc 把修改本次请求 header 的函数转为 ReqOption,用于迁移旧的 func(http.Header) 形式的自定义选项
+func ReqHeaderFunc(fn func(http.Header)) ReqOption {
+	return func(ro *requestOptions) { fn(ro.header) }
+}
 
 // WithReqHeader 为本次请求附加一个 header
 func WithReqHeader(key, value string) ReqOption {
-	return func(h http.Header) { h.Set(key, value) }
+	return func(ro *requestOptions) { ro.header.Set(key, value) }
 }
 
 // WithReqHeaders 为本次请求附加一组 header(map[string][]string 可直接传入)
 func WithReqHeaders(headers http.Header) ReqOption {
-	return func(h http.Header) {
+	return func(ro *requestOptions) {
 		for k, vs := range headers {
 			for _, v := range vs {
-				h.Add(k, v)
+				ro.header.Add(k, v)
 			}
 		}
 	}
 }
 
-// buildHeader 把 contentType 和请求级 option 合成 header
-func buildHeader(contentType string, opts []ReqOption) http.Header {
-	header := http.Header{}
-	if contentType != "" {
-		header.Set("Content-Type", contentType)
-	}
-	for _, opt := range opts {
-		opt(header)
+// WithReqRetry 本次请求使用指定的重试策略,覆盖客户端配置;RetryPolicy{} 表示不重试
+func WithReqRetry(p RetryPolicy) ReqOption {
+	return func(ro *requestOptions) { ro.retry = &p }
+}
+
+// WithIdempotencyKey 为本次请求设置幂等键(Idempotency-Key header),带幂等键的 POST/PATCH 请求允许重试
+func WithIdempotencyKey(key string) ReqOption {
+	return WithReqHeader(DefaultIdempotencyHeader, key)
+}
+
+// contentType 生成只含 Content-Type 的 header
+func contentType(ct string) http.Header {
+	if ct == "" {
+		return nil
 	}
-	return header
+	return http.Header{"Content-Type": {ct}}
 }
 
 // Get 发起 GET 请求
 func (c *Client) Get(ctx context.Context, url string, opts ...ReqOption) (*Response, error) {
-	return c.Do(ctx, http.MethodGet, url, nil, buildHeader("", opts))
+	return c.Do(ctx, http.MethodGet, url, nil, nil, opts...)
 }
 
-// GetBytes 发起 GET 请求并返回响应 body(非 2xx 返回错误),适合下载文件/原始内容
+// GetBytes 发起 GET 请求并返回响应 body(非 2xx 返回 *Error),适合下载文件/原始内容
 func (c *Client) GetBytes(ctx context.Context, url string, opts ...ReqOption) ([]byte, error) {
 	resp, err := c.Get(ctx, url, opts...)
 	if err != nil {
 		return nil, err
 	}
-	if !resp.OK() {
-		return nil, fmt.Errorf("http 状态码 %d: %s", resp.StatusCode, truncate(resp.String(), 200))
+	if err := resp.Err(); err != nil {
+		return nil, err
 	}
 	return resp.Body, nil
 }
 
 // Post 发起 POST 请求
-func (c *Client) Post(ctx context.Context, url string, contentType string, body io.Reader, opts ...ReqOption) (*Response, error) {
-	return c.Do(ctx, http.MethodPost, url, body, buildHeader(contentType, opts))
+func (c *Client) Post(ctx context.Context, url string, ct string, body io.Reader, opts ...ReqOption) (*Response, error) {
+	return c.Do(ctx, http.MethodPost, url, body, contentType(ct), opts...)
 }
 
 // PostForm 发起表单 POST 请求
@@ -355,7 +527,7 @@ func (c *Client) PutJSON(ctx context.Context, url string, in any, out any, opts
 	if err != nil {
 		return err
 	}
-	resp, err := c.Do(ctx, http.MethodPut, url, body, buildHeader("application/json", opts))
+	resp, err := c.Do(ctx, http.MethodPut, url, body, contentType("application/json"), opts...)
 	if err != nil {
 		return err
 	}
@@ -373,15 +545,24 @@ func marshalJSONBody(in any) (io.Reader, error) {
 	return bytes.NewReader(data), nil
 }
 
+// decodeJSONResponse 非 2xx 返回 KindStatus 的 *Error,解析失败返回 KindDecode 的 *Error
 func decodeJSONResponse(resp *Response, out any) error {
-	if !resp.OK() {
-		return fmt.Errorf("http 状态码 %d: %s", resp.StatusCode, truncate(resp.String(), 200))
+	if err := resp.Err(); err != nil {
+		return err
 	}
 	if out == nil {
 		return nil
 	}
 	if err := json.Unmarshal(resp.Body, out); err != nil {
-		return fmt.Errorf("响应 JSON 解析失败: %w (body: %s)", err, truncate(resp.String(), 200))
+		return &Error{
+			Kind:       KindDecode,
+			Method:     resp.method,
+			URL:        resp.url,
+			StatusCode: resp.StatusCode,
+			Body:       truncate(resp.String(), bodySnippetSize),
+			Attempts:   resp.attempts,
+			Err:        err,
+		}
 	}
 	return nil
 }
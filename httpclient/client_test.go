@@ -145,7 +145,7 @@ func TestDefaultHeaders(t *testing.T) {
 // TestReqHeaderOptions 验证单次请求 header 选项
 func TestReqHeaderOptions(t *testing.T) {
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.Header.Get("X-Custom") != "abc" || r.Header.Get("Accept-Language") != "zh-CN" {
+		if r.Header.Get("X-Custom") != "abc" || r.Header.Get("Accept-Language") != "zh-CN" || r.Header.Get("X-Tenant") != "t1" {
 			t.Errorf("缺少请求级 header: %v", r.Header)
 		}
 		w.WriteHeader(200)
@@ -158,6 +158,7 @@ func TestReqHeaderOptions(t *testing.T) {
 	err := New().GetJSON(t.Context(), srv.URL, nil,
 		WithReqHeader("X-Custom", "abc"),
 		WithReqHeaders(extra),
+		ReqHeaderFunc(func(h http.Header) { h.Set("X-Tenant", "t1") }),
 	)
 	if err != nil {
 		t.Fatalf("GetJSON error: %v", err)
@@ -0,0 +1,230 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// clientConfig [httpclient.xxx] 配置
+type clientConfig struct {
+	BaseURL         string            `json:"base_url" toml:"base_url" mapstructure:"base_url"`
+	Timeout         int               `json:"timeout" toml:"timeout" mapstructure:"timeout"` // 毫秒,默认 10000
+	Retries         int               `json:"retries" toml:"retries" mapstructure:"retries"` // 按 DefaultRetryPolicy 重试的次数,0 不重试
+	Proxy           string            `json:"proxy" toml:"proxy" mapstructure:"proxy"`
+	UserAgent       string            `json:"user_agent" toml:"user_agent" mapstructure:"user_agent"`
+	Headers         map[string]string `json:"headers" toml:"headers" mapstructure:"headers"`
+	MaxResponseSize int64             `json:"max_response_size" toml:"max_response_size" mapstructure:"max_response_size"` // 字节,0 不限制
+	AccessLog       bool              `json:"access_log" toml:"access_log" mapstructure:"access_log"`
+	HedgeDelay      int               `json:"hedge_delay" toml:"hedge_delay" mapstructure:"hedge_delay"` // 毫秒,请求多久未返回发出对冲请求,0 不对冲
+	MaxHedges       int               `json:"max_hedges" toml:"max_hedges" mapstructure:"max_hedges"`    // 最多对冲请求数,默认 1
+
+	TLSCAFile             string `json:"tls_ca_file" toml:"tls_ca_file" mapstructure:"tls_ca_file"`       // 自定义 CA(PEM),为空使用系统根证书
+	TLSCertFile           string `json:"tls_cert_file" toml:"tls_cert_file" mapstructure:"tls_cert_file"` // 客户端证书(双向 TLS),需与 tls_key_file 同时配置
+	TLSKeyFile            string `json:"tls_key_file" toml:"tls_key_file" mapstructure:"tls_key_file"`
+	TLSServerName         string `json:"tls_server_name" toml:"tls_server_name" mapstructure:"tls_server_name"`
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify" toml:"tls_insecure_skip_verify" mapstructure:"tls_insecure_skip_verify"`
+	TLSMinVersion         string `json:"tls_min_version" toml:"tls_min_version" mapstructure:"tls_min_version"`             // "1.2"(默认) 或 "1.3"
+	TLSReloadInterval     int    `json:"tls_reload_interval" toml:"tls_reload_interval" mapstructure:"tls_reload_interval"` // 毫秒,证书文件变更检查间隔,默认 60000,-1 不重载
+
+	Resolver      string `json:"resolver" toml:"resolver" mapstructure:"resolver"`                   // "consul" 开启服务发现,svc.<服务名> 地址按实例负载均衡
+	ConsulAddress string `json:"consul_address" toml:"consul_address" mapstructure:"consul_address"` // 为空使用 consul 默认地址
+	ConsulTag     string `json:"consul_tag" toml:"consul_tag" mapstructure:"consul_tag"`
+	LoadBalance   string `json:"load_balance" toml:"load_balance" mapstructure:"load_balance"`       // round_robin(默认) 或 least_pending
+	EjectFailures int    `json:"eject_failures" toml:"eject_failures" mapstructure:"eject_failures"` // 实例连续失败多少次摘除,默认 5,-1 不摘除
+	EjectDuration int    `json:"eject_duration" toml:"eject_duration" mapstructure:"eject_duration"` // 毫秒,默认 30000
+
+	CircuitBreaker     bool    `json:"circuit_breaker" toml:"circuit_breaker" mapstructure:"circuit_breaker"`
+	BreakerRatio       float64 `json:"breaker_failure_ratio" toml:"breaker_failure_ratio" mapstructure:"breaker_failure_ratio"` // 默认 0.5
+	BreakerMinRequests int     `json:"breaker_min_requests" toml:"breaker_min_requests" mapstructure:"breaker_min_requests"`    // 默认 20
+	BreakerWindow      int     `json:"breaker_window" toml:"breaker_window" mapstructure:"breaker_window"`                      // 毫秒,默认 10000
+	BreakerOpenTimeout int     `json:"breaker_open_timeout" toml:"breaker_open_timeout" mapstructure:"breaker_open_timeout"`    // 毫秒,默认 30000
+}
+
+func (cc clientConfig) String() string {
+	data, _ := json.Marshal(cc)
+	return string(data)
+}
+
+// validate 校验配置
+func (cc *clientConfig) validate() error {
+	if cc.BaseURL != "" {
+		u, err := url.Parse(cc.BaseURL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("base_url %q 不是有效的地址", cc.BaseURL)
+		}
+	}
+	if cc.Proxy != "" {
+		if _, err := url.Parse(cc.Proxy); err != nil {
+			return fmt.Errorf("proxy %q 不是有效的地址: %w", cc.Proxy, err)
+		}
+	}
+	nonNegative := []struct {
+		key string
+		val int64
+	}{
+		{"timeout", int64(cc.Timeout)},
+		{"retries", int64(cc.Retries)},
+		{"max_response_size", cc.MaxResponseSize},
+		{"hedge_delay", int64(cc.HedgeDelay)},
+		{"max_hedges", int64(cc.MaxHedges)},
+		{"eject_duration", int64(cc.EjectDuration)},
+		{"breaker_min_requests", int64(cc.BreakerMinRequests)},
+		{"breaker_window", int64(cc.BreakerWindow)},
+		{"breaker_open_timeout", int64(cc.BreakerOpenTimeout)},
+	}
+	for _, item := range nonNegative {
+		if item.val < 0 {
+			return fmt.Errorf("%s 不能为负数: %d", item.key, item.val)
+		}
+	}
+	if cc.BreakerRatio < 0 || cc.BreakerRatio > 1 {
+		return fmt.Errorf("breaker_failure_ratio 只能在 0~1 之间: %v", cc.BreakerRatio)
+	}
+	return nil
+}
+
+// tlsOptions 根据 tls_* 配置生成 TLS 配置项
+// 证书文件在这里就读取校验,配置错误在加载阶段暴露;之后文件变更自动热重载
+func (cc *clientConfig) tlsOptions() ([]Option, error) {
+	var opts []Option
+	if cc.TLSServerName != "" {
+		// WithTLSConfig 完全替换 TLS 配置,需放在其它 TLS 选项之前
+		opts = append(opts, WithTLSConfig(&tls.Config{ServerName: cc.TLSServerName}))
+	}
+	if cc.TLSInsecureSkipVerify {
+		opts = append(opts, WithInsecureSkipVerify())
+	}
+	switch cc.TLSMinVersion {
+	case "", "1.2":
+	case "1.3":
+		opts = append(opts, WithMinTLSVersion(tls.VersionTLS13))
+	default:
+		return nil, fmt.Errorf("tls_min_version 只能是 1.2 或 1.3: %q", cc.TLSMinVersion)
+	}
+	if cc.TLSCAFile == "" && cc.TLSCertFile == "" && cc.TLSKeyFile == "" {
+		return opts, nil
+	}
+	if err := checkTLSFiles(cc.TLSCAFile, cc.TLSCertFile, cc.TLSKeyFile); err != nil {
+		return nil, err
+	}
+	if cc.TLSCAFile != "" {
+		opts = append(opts, WithRootCAs(cc.TLSCAFile))
+	}
+	if cc.TLSCertFile != "" {
+		opts = append(opts, WithClientCertificate(cc.TLSCertFile, cc.TLSKeyFile))
+	}
+	if cc.TLSReloadInterval != 0 {
+		opts = append(opts, WithTLSReloadInterval(time.Duration(cc.TLSReloadInterval)*time.Millisecond))
+	}
+	return opts, nil
+}
+
+// options 把配置转换为客户端配置项
+func (cc *clientConfig) options() ([]Option, error) {
+	if err := cc.validate(); err != nil {
+		return nil, err
+	}
+	var opts []Option
+	if cc.BaseURL != "" {
+		opts = append(opts, WithBaseURL(cc.BaseURL))
+	}
+	if cc.Timeout > 0 {
+		opts = append(opts, WithTimeout(time.Duration(cc.Timeout)*time.Millisecond))
+	}
+	if cc.Retries > 0 {
+		p := DefaultRetryPolicy()
+		p.MaxRetries = cc.Retries
+		opts = append(opts, WithRetryPolicy(p))
+	}
+	if cc.Proxy != "" {
+		opts = append(opts, WithProxyURL(cc.Proxy))
+	}
+	if cc.UserAgent != "" {
+		opts = append(opts, WithUserAgent(cc.UserAgent))
+	}
+	for k, v := range cc.Headers {
+		opts = append(opts, WithHeader(k, v))
+	}
+	if cc.MaxResponseSize > 0 {
+		opts = append(opts, WithMaxResponseSize(cc.MaxResponseSize))
+	}
+	if cc.AccessLog {
+		opts = append(opts, WithAccessLog())
+	}
+	if cc.HedgeDelay > 0 {
+		opts = append(opts, WithHedging(HedgePolicy{Delay: time.Duration(cc.HedgeDelay) * time.Millisecond, MaxHedges: cc.MaxHedges}))
+	}
+	tlsOpts, err := cc.tlsOptions()
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, tlsOpts...)
+	if cc.Resolver != "" {
+		opt, err := cc.resolverOption()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+	if cc.CircuitBreaker {
+		var bopts []BreakerOption
+		if cc.BreakerRatio > 0 {
+			bopts = append(bopts, WithFailureRatio(cc.BreakerRatio))
+		}
+		if cc.BreakerMinRequests > 0 {
+			bopts = append(bopts, WithMinRequests(cc.BreakerMinRequests))
+		}
+		if cc.BreakerWindow > 0 {
+			bopts = append(bopts, WithBreakerWindow(time.Duration(cc.BreakerWindow)*time.Millisecond))
+		}
+		if cc.BreakerOpenTimeout > 0 {
+			bopts = append(bopts, WithOpenTimeout(time.Duration(cc.BreakerOpenTimeout)*time.Millisecond))
+		}
+		opts = append(opts, WithCircuitBreaker(bopts...))
+	}
+	return opts, nil
+}
+
+// resolverOption 根据 resolver 配置生成服务发现配置项
+func (cc *clientConfig) resolverOption() (Option, error) {
+	if cc.Resolver != "consul" {
+		return nil, fmt.Errorf("resolver 只支持 consul: %q", cc.Resolver)
+	}
+	var bopts []BalancerOption
+	switch LoadBalance(cc.LoadBalance) {
+	case "":
+	case RoundRobin, LeastPending:
+		bopts = append(bopts, WithLoadBalance(LoadBalance(cc.LoadBalance)))
+	default:
+		return nil, fmt.Errorf("load_balance 只能是 %s 或 %s: %q", RoundRobin, LeastPending, cc.LoadBalance)
+	}
+	if cc.EjectFailures != 0 || cc.EjectDuration != 0 {
+		failures, d := DefaultEjectFailures, DefaultEjectDuration
+		if cc.EjectFailures != 0 {
+			failures = cc.EjectFailures
+		}
+		if cc.EjectDuration > 0 {
+			d = time.Duration(cc.EjectDuration) * time.Millisecond
+		}
+		bopts = append(bopts, WithOutlierEjection(failures, d))
+	}
+	r, err := NewConsulResolver(cc.ConsulAddress, WithConsulTag(cc.ConsulTag))
+	if err != nil {
+		return nil, err
+	}
+	return WithResolver(r, bopts...), nil
+}
+
+// normalize 去除字符串配置首尾空格
+func (cc *clientConfig) normalize() {
+	cc.BaseURL = strings.TrimSpace(cc.BaseURL)
+	cc.Proxy = strings.TrimSpace(cc.Proxy)
+	cc.TLSCAFile = strings.TrimSpace(cc.TLSCAFile)
+	cc.TLSCertFile = strings.TrimSpace(cc.TLSCertFile)
+	cc.TLSKeyFile = strings.TrimSpace(cc.TLSKeyFile)
+	cc.TLSServerName = strings.TrimSpace(cc.TLSServerName)
+}
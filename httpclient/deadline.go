@@ -0,0 +1,34 @@
+package httpclient
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeadlineHeader 透传给下游的剩余超时,值为毫秒数(相对时间,不受服务器间时钟偏差影响)。
+// igo 的 web 层(web.Deadline 中间件)收到后据此为请求 context 设置截止时间,下游再调用其它服务时继续透传
+const DeadlineHeader = "X-Request-Deadline"
+
+// WithDeadlinePropagation 是否透传剩余超时到下游(默认开启)。
+// 剩余超时取 ctx 截止时间和 WithTimeout 中较早的一个;调用第三方服务时可关闭
+func WithDeadlinePropagation(enabled bool) Option {
+	return func(c *Client) { c.noDeadline = !enabled }
+}
+
+// setDeadline 设置 DeadlineHeader;timeout 为本次请求的整体超时,0 表示只看 ctx
+func (c *Client) setDeadline(req *http.Request, timeout time.Duration) {
+	if c.noDeadline {
+		return
+	}
+	deadline, ok := req.Context().Deadline()
+	remaining := time.Until(deadline)
+	if timeout > 0 && (!ok || timeout < remaining) {
+		remaining = timeout
+	} else if !ok {
+		return
+	}
+	if ms := remaining.Milliseconds(); ms > 0 {
+		req.Header.Set(DeadlineHeader, strconv.FormatInt(ms, 10))
+	}
+}
@@ -0,0 +1,141 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+)
+
+// ErrorKind 错误分类
+type ErrorKind string
+
+const (
+	KindTimeout    ErrorKind = "timeout"    // 超时(WithTimeout、ctx 超时、连接/握手超时)
+	KindCanceled   ErrorKind = "canceled"   // ctx 被取消
+	KindConnection ErrorKind = "connection" // 请求未得到响应:DNS 解析、建连、TLS、连接被重置等
+	KindStatus     ErrorKind = "status"     // 响应状态码非 2xx
+	KindDecode     ErrorKind = "decode"     // 响应体 JSON 解析失败
+)
+
+// bodySnippetSize Error 中保留的响应体长度
+const bodySnippetSize = 200
+
+// Error httpclient 请求错误,可用 errors.As 获取:
+//
+//	var herr *httpclient.Error
+//	if errors.As(err, &herr) && herr.StatusCode == http.StatusConflict { ... }
+//
+// 熔断(ErrCircuitOpen)、响应体超限(ErrResponseTooLarge)等不是由一次请求结果产生的错误不包装
+type Error struct {
+	Kind       ErrorKind
+	Method     string
+	URL        string
+	StatusCode int    // 收到响应时的状态码,未收到响应为 0
+	Body       string // 响应体片段(最多 200 字节)
+	Attempts   int    // 尝试次数(含重试)
+	Err        error  // 底层错误,KindStatus 时为 nil
+}
+
+func (e *Error) Error() string {
+	switch e.Kind {
+	case KindStatus:
+		return fmt.Sprintf("http 状态码 %d: %s %s: %s", e.StatusCode, e.Method, e.URL, e.Body)
+	case KindDecode:
+		return fmt.Sprintf("响应 JSON 解析失败: %s %s: %v (body: %s)", e.Method, e.URL, e.Err, e.Body)
+	}
+	err := e.Err
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		err = uerr.Err // url.Error 的信息中已有 method 和 url
+	}
+	return fmt.Sprintf("httpclient %s %s 请求失败(%s, 尝试 %d 次): %v", e.Method, e.URL, e.Kind, e.Attempts, err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Timeout 是否为超时错误
+func (e *Error) Timeout() bool {
+	return e.Kind == KindTimeout
+}
+
+// newRequestError 包装未得到响应的请求错误;已经是 *Error 或不属于单次请求结果的错误原样返回
+func newRequestError(method, rawurl string, attempts int, err error) error {
+	var herr *Error
+	if err == nil || errors.As(err, &herr) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrResponseTooLarge) {
+		return err
+	}
+	return &Error{Kind: classify(err), Method: method, URL: rawurl, Attempts: attempts, Err: err}
+}
+
+// classify 判断请求错误的分类
+func classify(err error) ErrorKind {
+	if errors.Is(err, context.Canceled) {
+		return KindCanceled
+	}
+	var nerr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &nerr) && nerr.Timeout() {
+		return KindTimeout
+	}
+	return KindConnection
+}
+
+// Err 状态码非 2xx 时返回 *Error(KindStatus),否则返回 nil
+func (r *Response) Err() error {
+	if r.OK() {
+		return nil
+	}
+	return &Error{
+		Kind:       KindStatus,
+		Method:     r.method,
+		URL:        r.url,
+		StatusCode: r.StatusCode,
+		Body:       truncate(string(r.Body), bodySnippetSize),
+		Attempts:   r.attempts,
+	}
+}
+
+// StatusCode 返回 err 中的 HTTP 状态码,没有响应时返回 0
+func StatusCode(err error) int {
+	var herr *Error
+	if errors.As(err, &herr) {
+		return herr.StatusCode
+	}
+	return 0
+}
+
+// IsKind err 是否为 kind 分类的 *Error
+func IsKind(err error, kind ErrorKind) bool {
+	var herr *Error
+	return errors.As(err, &herr) && herr.Kind == kind
+}
+
+// IsNotFound 状态码是否为 404
+func IsNotFound(err error) bool {
+	return IsKind(err, KindStatus) && StatusCode(err) == http.StatusNotFound
+}
+
+// IsClientError 状态码是否为 4xx
+func IsClientError(err error) bool {
+	code := StatusCode(err)
+	return IsKind(err, KindStatus) && code >= 400 && code < 500
+}
+
+// IsServerError 状态码是否为 5xx
+func IsServerError(err error) bool {
+	return IsKind(err, KindStatus) && StatusCode(err) >= 500
+}
+
+// IsTimeout 是否为超时错误
+func IsTimeout(err error) bool {
+	return IsKind(err, KindTimeout)
+}
+
+// IsConnectionError 是否为 DNS 解析、建连、TLS 等未得到响应的错误
+func IsConnectionError(err error) bool {
+	return IsKind(err, KindConnection)
+}
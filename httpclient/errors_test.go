@@ -0,0 +1,87 @@
+package httpclient
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/context"
+)
+
+func TestErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.Error(w, "order not found", http.StatusNotFound)
+		case "/bad-json":
+			w.Write([]byte("<html>"))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+	c := New(WithRetryPolicy(RetryPolicy{MaxRetries: 1, RetryStatuses: []int{http.StatusBadGateway}}))
+
+	var out map[string]any
+	err := c.GetJSON(context.Background(), srv.URL+"/missing", &out)
+	var herr *Error
+	if !errors.As(err, &herr) {
+		t.Fatalf("error 类型 = %T, want *Error", err)
+	}
+	if herr.Kind != KindStatus || herr.StatusCode != 404 || herr.Method != http.MethodGet ||
+		herr.URL != srv.URL+"/missing" || !strings.Contains(herr.Body, "order not found") || herr.Attempts != 1 {
+		t.Errorf("error = %+v", herr)
+	}
+	if !IsNotFound(err) || !IsClientError(err) || IsServerError(err) || StatusCode(err) != 404 {
+		t.Error("404 辅助函数判断错误")
+	}
+
+	// 重试后的状态码错误记录尝试次数
+	_, err = c.GetBytes(context.Background(), srv.URL+"/fail")
+	if !errors.As(err, &herr) || herr.Attempts != 2 || !IsServerError(err) {
+		t.Errorf("error = %v", err)
+	}
+
+	err = c.GetJSON(context.Background(), srv.URL+"/bad-json", &out)
+	if !IsKind(err, KindDecode) || StatusCode(err) != 200 {
+		t.Errorf("解析失败 error = %v", err)
+	}
+}
+
+func TestErrorTransport(t *testing.T) {
+	// 端口未监听:连接失败
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	_, err = New(WithRetries(1)).Get(context.Background(), "http://"+addr)
+	var herr *Error
+	if !errors.As(err, &herr) || herr.Kind != KindConnection || herr.Attempts != 2 || !IsConnectionError(err) {
+		t.Errorf("连接失败 error = %v", err)
+	}
+
+	// 超时
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+	_, err = New(WithTimeout(20*time.Millisecond)).Get(context.Background(), srv.URL)
+	if !IsTimeout(err) {
+		t.Errorf("超时 error = %v", err)
+	}
+	var nerr net.Error
+	if !errors.As(err, &nerr) || !nerr.Timeout() {
+		t.Error("底层错误应可通过 errors.As 获取")
+	}
+
+	// 熔断等错误不包装
+	if wrapped := newRequestError("GET", "http://x", 1, ErrCircuitOpen); wrapped != ErrCircuitOpen {
+		t.Errorf("ErrCircuitOpen 不应被包装: %v", wrapped)
+	}
+}
@@ -0,0 +1,137 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/aichy126/igo/metrics"
+)
+
+var (
+	hedgeRequests = metrics.NewCounterVec("igo_httpclient_hedge_requests_total",
+		"httpclient 发出的对冲请求数", "host")
+	hedgeWins = metrics.NewCounterVec("igo_httpclient_hedge_wins_total",
+		"httpclient 对冲请求先于原请求成功返回的次数", "host")
+)
+
+// HedgePolicy 对冲策略:请求在 Delay 内没有返回时再发一个相同的请求,取先成功返回的响应,其余取消;
+// 返回需要重试的状态码或 5xx 的请求不算成功。
+// 用于降低尾延迟,会增加上游压力,Delay 建议设为上游 P95 耗时左右。
+// 只对幂等请求(GET/PUT/DELETE 等,或带幂等键)生效;每次重试内部独立对冲
+type HedgePolicy struct {
+	Delay     time.Duration // 发出下一个对冲请求前的等待时间,<= 0 不对冲
+	MaxHedges int           // 最多额外发出的请求数,默认 1
+	// Hosts 对冲请求改发到的备用地址(如 "https://backup.example.com"),按顺序轮流使用;
+	// 为空时发往原地址(开启 WithResolver 时会选到其它实例)
+	Hosts []string
+}
+
+// WithHedging 设置客户端默认的对冲策略,可通过 WithReqHedging 按请求覆盖
+func WithHedging(p HedgePolicy) Option {
+	return func(c *Client) { c.hedge = p }
+}
+
+// WithReqHedging 本次请求使用指定的对冲策略;HedgePolicy{} 表示不对冲
+func WithReqHedging(p HedgePolicy) ReqOption {
+	return func(ro *requestOptions) { ro.hedge = &p }
+}
+
+// enabled 请求是否对冲:策略开启且请求幂等
+func (p HedgePolicy) enabled(method string, header http.Header, idempotencyHeader string) bool {
+	return p.Delay > 0 && RetryPolicy{IdempotencyHeader: idempotencyHeader}.canRetry(method, header)
+}
+
+type hedgeResult struct {
+	resp  *Response
+	err   error
+	host  string // 请求发往的 host
+	hedge bool   // 是否为对冲请求
+}
+
+// failed 传输失败,或状态码为需要重试的状态码/5xx:不算成功,继续等待其它请求
+func (r hedgeResult) failed(retry RetryPolicy) bool {
+	return r.err != nil || retry.retryStatus(r.resp.StatusCode) || r.resp.StatusCode >= http.StatusInternalServerError
+}
+
+// hedged 按对冲策略发起一次尝试:先发原请求,每隔 Delay 没有成功响应就再发一个,
+// 某个请求失败(含需要重试的状态码和 5xx)时立即发出下一个;返回第一个成功的响应,
+// 全部失败时优先返回最后一个失败的响应(由重试逻辑按状态码处理),都没有响应时返回最后一个错误
+func (c *Client) hedged(ctx context.Context, host, method, rawurl string, body []byte, header http.Header, ro *requestOptions, n int, p HedgePolicy, retry RetryPolicy) (*Response, error) {
+	maxHedges := p.MaxHedges
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+	// 返回时取消其余仍在进行的请求;它们不计入熔断统计
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, maxHedges+1)
+	launched := 0
+	launch := func() {
+		target, targetHost := rawurl, host
+		if launched > 0 {
+			target, targetHost = p.hedgeTarget(rawurl, host, launched)
+			hedgeRequests.WithLabelValues(targetHost).Inc()
+		}
+		hedge := launched > 0
+		launched++
+		go func() {
+			resp, err := c.attempt(ctx, targetHost, method, target, body, header, ro, n)
+			results <- hedgeResult{resp: resp, err: err, host: targetHost, hedge: hedge}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(p.Delay)
+	defer timer.Stop()
+	var last, lastResp hedgeResult
+	for done := 0; done < launched; {
+		select {
+		case r := <-results:
+			done++
+			if !r.failed(retry) {
+				if r.hedge {
+					hedgeWins.WithLabelValues(r.host).Inc()
+				}
+				return r.resp, nil
+			}
+			last = r
+			if r.err == nil {
+				lastResp = r
+			}
+			// 失败时不必等待 Delay,直接发出下一个
+			if launched <= maxHedges && ctx.Err() == nil {
+				launch()
+				timer.Reset(p.Delay)
+			}
+		case <-timer.C:
+			if launched <= maxHedges {
+				launch()
+				timer.Reset(p.Delay)
+			}
+		}
+	}
+	if lastResp.resp != nil {
+		return lastResp.resp, nil
+	}
+	return last.resp, last.err
+}
+
+// hedgeTarget 第 i 个对冲请求(从 1 开始)的地址和 host
+func (p HedgePolicy) hedgeTarget(rawurl, host string, i int) (string, string) {
+	if len(p.Hosts) == 0 {
+		return rawurl, host
+	}
+	alt, err := url.Parse(p.Hosts[(i-1)%len(p.Hosts)])
+	if err != nil || alt.Host == "" {
+		return rawurl, host
+	}
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl, host
+	}
+	u.Scheme, u.Host = alt.Scheme, alt.Host
+	return u.String(), alt.Host
+}
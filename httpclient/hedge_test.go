@@ -0,0 +1,175 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/context"
+)
+
+func TestHedging(t *testing.T) {
+	var calls atomic.Int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一个请求很慢,对冲请求很快
+		if calls.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer slow.Close()
+
+	c := New(WithHedging(HedgePolicy{Delay: 20 * time.Millisecond}))
+	start := time.Now()
+	resp, err := c.Get(context.Background(), slow.URL)
+	if err != nil || resp.String() != "ok" {
+		t.Fatalf("resp = %v, err = %v", resp, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("对冲后耗时 %v,应远小于 1s", elapsed)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("请求次数 = %d, want 2", calls.Load())
+	}
+
+	// 非幂等请求不对冲
+	calls.Store(1) // 之后的请求都很快
+	var posts atomic.Int32
+	post := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posts.Add(1)
+		time.Sleep(50 * time.Millisecond)
+	}))
+	defer post.Close()
+	if _, err := c.Post(context.Background(), post.URL, "text/plain", nil); err != nil {
+		t.Fatal(err)
+	}
+	if posts.Load() != 1 {
+		t.Errorf("POST 请求次数 = %d, want 1", posts.Load())
+	}
+}
+
+func TestHedgingAlternateHost(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+		w.Write([]byte("primary"))
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("backup:" + r.URL.Path))
+	}))
+	defer backup.Close()
+
+	c := New()
+	resp, err := c.Get(context.Background(), primary.URL+"/v1/x",
+		WithReqHedging(HedgePolicy{Delay: 10 * time.Millisecond, Hosts: []string{backup.URL}}))
+	if err != nil || resp.String() != "backup:/v1/x" {
+		t.Errorf("resp = %v, err = %v", resp, err)
+	}
+	// 胜出次数与对冲请求数使用同一个 host 标签
+	backupHost := strings.TrimPrefix(backup.URL, "http://")
+	if v := hedgeWins.WithLabelValues(backupHost).Value(); v != 1 {
+		t.Errorf("备用地址的对冲胜出次数 = %v, want 1", v)
+	}
+	if v := hedgeRequests.WithLabelValues(backupHost).Value(); v != 1 {
+		t.Errorf("备用地址的对冲请求数 = %v, want 1", v)
+	}
+}
+
+func TestHedgingIgnores5xx(t *testing.T) {
+	// 对冲请求立即返回 503,原请求稍后返回 200:应取原请求的响应
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("primary"))
+	}))
+	defer primary.Close()
+	var backupCalls atomic.Int32
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backupCalls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer backup.Close()
+
+	c := New(WithRetryPolicy(RetryPolicy{}))
+	p := HedgePolicy{Delay: 20 * time.Millisecond, Hosts: []string{backup.URL}}
+	resp, err := c.Get(context.Background(), primary.URL, WithReqHedging(p))
+	if err != nil || resp.StatusCode != http.StatusOK || resp.String() != "primary" {
+		t.Fatalf("resp = %v, err = %v", resp, err)
+	}
+	if backupCalls.Load() != 1 {
+		t.Errorf("备用地址请求次数 = %d, want 1", backupCalls.Load())
+	}
+	if v := hedgeWins.WithLabelValues(strings.TrimPrefix(backup.URL, "http://")).Value(); v != 0 {
+		t.Errorf("503 的对冲请求不应计为胜出: %v", v)
+	}
+
+	// 都返回 5xx 时返回失败的响应,由调用方按状态码处理
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer down.Close()
+	resp, err = c.Get(context.Background(), down.URL, WithReqHedging(p))
+	if err != nil || resp.StatusCode < 500 {
+		t.Errorf("resp = %v, err = %v", resp, err)
+	}
+}
+
+func TestHedgingFailFast(t *testing.T) {
+	// 原请求连接失败时立即发出对冲请求,不等待 Delay
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("backup"))
+	}))
+	defer backup.Close()
+	c := New(WithHedging(HedgePolicy{Delay: time.Hour, Hosts: []string{backup.URL}}))
+	start := time.Now()
+	resp, err := c.Get(context.Background(), "http://127.0.0.1:1/")
+	if err != nil || resp.String() != "backup" || time.Since(start) > time.Second {
+		t.Errorf("resp = %v, err = %v", resp, err)
+	}
+}
+
+func TestDeadlineHeader(t *testing.T) {
+	var got atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Store(r.Header.Get(DeadlineHeader))
+	}))
+	defer srv.Close()
+	remaining := func() int {
+		t.Helper()
+		ms, _ := strconv.Atoi(got.Load().(string))
+		return ms
+	}
+
+	// 取 WithTimeout 和 ctx 截止时间中较早的一个
+	c := New(WithTimeout(2 * time.Second))
+	if _, err := c.Get(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if ms := remaining(); ms <= 1900 || ms > 2000 {
+		t.Errorf("无 ctx 截止时间时 = %dms, want ~2000", ms)
+	}
+	ctx, cancel := context.Background().WithTimeout(300 * time.Millisecond)
+	defer cancel()
+	if _, err := c.Get(ctx, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if ms := remaining(); ms <= 0 || ms > 300 {
+		t.Errorf("ctx 截止时间 = %dms, want <= 300", ms)
+	}
+
+	if _, err := New(WithDeadlinePropagation(false)).Get(context.Background(), srv.URL); err != nil {
+		t.Fatal(err)
+	}
+	if v := got.Load().(string); v != "" {
+		t.Errorf("关闭透传后 header = %q", v)
+	}
+}
@@ -0,0 +1,112 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/aichy126/igo/config"
+	"github.com/aichy126/igo/log"
+)
+
+// Manager 按名称管理 [httpclient.xxx] 配置的客户端,配置热重载时重建有变化的客户端
+type Manager struct {
+	conf *config.Config
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+	configs map[string]string // 配置快照,用于判断是否变化
+}
+
+// NewManager 加载所有 [httpclient.xxx] 配置,任一配置错误返回错误;没有配置时返回空 Manager
+func NewManager(conf *config.Config) (*Manager, error) {
+	m := &Manager{conf: conf, clients: make(map[string]*Client), configs: make(map[string]string)}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload 重新读取配置:新增的创建,变化的重建,删除的移除;任一配置错误时保持原有客户端不变
+func (m *Manager) Reload() error {
+	raw := make(map[string]*clientConfig)
+	if err := m.conf.UnmarshalKey("httpclient", &raw); err != nil {
+		return fmt.Errorf("httpclient 配置解析失败: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clients := make(map[string]*Client, len(raw))
+	configs := make(map[string]string, len(raw))
+	for name, cc := range raw {
+		if cc == nil {
+			cc = &clientConfig{}
+		}
+		cc.normalize()
+		snapshot := cc.String()
+		if old, ok := m.clients[name]; ok && m.configs[name] == snapshot {
+			clients[name], configs[name] = old, snapshot
+			continue
+		}
+		opts, err := cc.options()
+		if err != nil {
+			return fmt.Errorf("httpclient 配置 [httpclient.%s] 解析失败: %w", name, err)
+		}
+		clients[name], configs[name] = New(append(opts, WithMetrics(name))...), snapshot
+	}
+	// 被替换或移除的客户端:进行中的请求不受影响,只关闭空闲连接
+	for name, old := range m.clients {
+		if clients[name] != old {
+			old.CloseIdleConnections()
+			log.Info("httpclient 配置已更新", log.String("name", name))
+		}
+	}
+	m.clients, m.configs = clients, configs
+	return nil
+}
+
+// Get 获取客户端;配置热重载后返回新的客户端,因此不要长期持有返回值
+func (m *Manager) Get(name string) (*Client, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.clients[name]
+	if !ok {
+		return nil, fmt.Errorf("httpclient 未配置 [httpclient.%s]", name)
+	}
+	return c, nil
+}
+
+// Names 所有客户端名称
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.clients))
+	for name := range m.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Close 关闭所有客户端的空闲连接
+func (m *Manager) Close() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, c := range m.clients {
+		c.CloseIdleConnections()
+	}
+	return nil
+}
+
+// CloseIdleConnections 关闭连接池中的空闲连接
+func (c *Client) CloseIdleConnections() {
+	c.hc.CloseIdleConnections()
+}
+
+// ErrorClient 返回所有请求都失败并返回 err 的客户端,用于获取客户端失败时仍可链式调用
+func ErrorClient(err error) *Client {
+	return New(WithMiddleware(func(RoundTripFunc) RoundTripFunc {
+		return func(*http.Request) (*http.Response, error) { return nil, err }
+	}))
+}
@@ -0,0 +1,137 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aichy126/igo/config"
+	"github.com/aichy126/igo/context"
+)
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManager(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"path":%q,"token":%q}`, r.URL.Path, r.Header.Get("X-Token"))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+	writeConfig(t, path, fmt.Sprintf(`
+[httpclient.payment]
+base_url = "%s/api/"
+timeout = 2000
+retries = 2
+circuit_breaker = true
+breaker_min_requests = 5
+
+[httpclient.payment.headers]
+X-Token = "v1"
+`, srv.URL))
+	conf, err := config.NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManager(conf)
+	if err != nil {
+		t.Fatalf("NewManager error: %v", err)
+	}
+	defer m.Close()
+
+	get := func() (out struct{ Path, Token string }) {
+		t.Helper()
+		c, err := m.Get("payment")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.GetJSON(context.Background(), "/v1/orders", &out); err != nil {
+			t.Fatalf("GetJSON error: %v", err)
+		}
+		return out
+	}
+	if out := get(); out.Path != "/api/v1/orders" || out.Token != "v1" {
+		t.Errorf("got %+v", out)
+	}
+	before, _ := m.Get("payment")
+	if before.retry.MaxRetries != 2 || before.breakers == nil {
+		t.Errorf("retries/circuit_breaker 未生效: retry=%+v", before.retry)
+	}
+
+	// 配置未变化时保留原客户端
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := m.Get("payment"); c != before {
+		t.Error("配置未变化不应重建客户端")
+	}
+
+	// 修改配置后热重载
+	writeConfig(t, path, fmt.Sprintf(`
+[httpclient.payment]
+base_url = "%s/api"
+
+[httpclient.payment.headers]
+X-Token = "v2"
+`, srv.URL))
+	if err := conf.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if out := get(); out.Token != "v2" {
+		t.Errorf("热重载后 token = %q, want v2", out.Token)
+	}
+
+	// 配置错误时保留旧客户端
+	current, _ := m.Get("payment")
+	writeConfig(t, path, `
+[httpclient.payment]
+base_url = "not a url"
+`)
+	if err := conf.ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Reload(); err == nil {
+		t.Error("错误配置应返回错误")
+	}
+	if c, _ := m.Get("payment"); c != current {
+		t.Error("配置错误时应保留旧客户端")
+	}
+
+	if _, err := m.Get("missing"); err == nil || !strings.Contains(err.Error(), "httpclient.missing") {
+		t.Errorf("未配置的客户端 error = %v", err)
+	}
+}
+
+func TestClientConfigErrors(t *testing.T) {
+	cases := map[string]*clientConfig{
+		"base_url 无效": {BaseURL: "/v1"},
+		"timeout 为负":  {Timeout: -1},
+		"熔断比例超出范围":    {CircuitBreaker: true, BreakerRatio: 1.5},
+		"证书缺少私钥":      {TLSCertFile: "client.pem"},
+		"CA 文件不存在":    {TLSCAFile: "/nonexistent/ca.pem"},
+	}
+	for name, cc := range cases {
+		if _, err := cc.options(); err == nil {
+			t.Errorf("%s: 应返回错误", name)
+		}
+	}
+}
+
+func TestErrorClient(t *testing.T) {
+	c := ErrorClient(fmt.Errorf("未配置"))
+	if _, err := c.Get(context.Background(), "http://127.0.0.1:1/"); err == nil || !strings.Contains(err.Error(), "未配置") {
+		t.Errorf("error = %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package httpclient
+
+import "net/http"
+
+// RoundTripFunc 发送一次 HTTP 请求
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Middleware 请求中间件:包装 next,可在请求前修改 req(签名、鉴权)、
+// 在请求后检查或替换响应(统计、改写),也可以不调用 next 直接返回(响应的 Body 不能为 nil)
+//
+//	sign := func(next httpclient.RoundTripFunc) httpclient.RoundTripFunc {
+//		return func(req *http.Request) (*http.Response, error) {
+//			req.Header.Set("X-Sign", sign(req))
+//			return next(req)
+//		}
+//	}
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// WithMiddleware 添加请求中间件,可多次调用;先添加的在外层。
+// 中间件位于重试和熔断之内,每次重试都会重新经过整条链;缓冲式请求的 body 可通过 req.GetBody 重复读取
+func WithMiddleware(mws ...Middleware) Option {
+	return func(c *Client) { c.middlewares = append(c.middlewares, mws...) }
+}
+
+// send 经过中间件链(开启服务发现时最后解析实例),用 hc 发送请求
+func (c *Client) send(hc *http.Client, req *http.Request) (*http.Response, error) {
+	next := RoundTripFunc(hc.Do)
+	if c.balancer != nil {
+		next = c.balancer.roundTrip(next)
+	}
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		next = c.middlewares[i](next)
+	}
+	return next(req)
+}
@@ -0,0 +1,90 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = io.WriteString(w, r.Header.Get("X-Order")+"|"+r.Header.Get("X-Sign")+"|"+string(body))
+	}))
+	defer srv.Close()
+
+	var order []string
+	var attempts atomic.Int32
+	tag := func(name string) Middleware {
+		return func(next RoundTripFunc) RoundTripFunc {
+			return func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				req.Header.Add("X-Order", name)
+				return next(req)
+			}
+		}
+	}
+	// 签名中间件:每次重试都重新读取 body 计算签名
+	sign := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			attempts.Add(1)
+			rc, _ := req.GetBody()
+			body, _ := io.ReadAll(rc)
+			req.Header.Set("X-Sign", "sig-"+string(body))
+			resp, err := next(req)
+			if err == nil {
+				resp.Header.Set("X-Seen", "1")
+			}
+			return resp, err
+		}
+	}
+
+	c := New(WithMiddleware(tag("a"), tag("b")), WithMiddleware(sign), WithRetryPolicy(fastPolicy()))
+	resp, err := c.Do(t.Context(), http.MethodPut, srv.URL, strings.NewReader("payload"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.String(); got != "a|sig-payload|payload" {
+		t.Errorf("body = %q", got)
+	}
+	if resp.Header.Get("X-Seen") != "1" {
+		t.Error("中间件应能修改响应")
+	}
+	if attempts.Load() != 2 || strings.Join(order, ",") != "a,b,a,b" {
+		t.Errorf("每次重试都应经过中间件链: attempts=%d order=%v", attempts.Load(), order)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	cached := func(next RoundTripFunc) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       io.NopCloser(strings.NewReader("cached")),
+				Request:    req,
+			}, nil
+		}
+	}
+	c := New(WithMiddleware(cached))
+	resp, err := c.Get(t.Context(), "http://127.0.0.1:1/unreachable")
+	if err != nil || resp.String() != "cached" {
+		t.Errorf("Get = %v, %v", resp, err)
+	}
+	stream, err := c.Stream(t.Context(), http.MethodGet, "http://127.0.0.1:1/unreachable", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if data, _ := io.ReadAll(stream.Body); string(data) != "cached" {
+		t.Errorf("Stream 也应经过中间件, body = %q", data)
+	}
+}
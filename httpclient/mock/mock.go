@@ -0,0 +1,314 @@
+// Package mock 为使用 httpclient 的代码提供测试替身:
+// 可编程的 Transport(按方法/路径/请求体匹配、固定响应、调用断言),
+// 以及把真实请求录制到文件、离线回放的 Recorder。
+//
+//	tr := mock.NewTransport()
+//	tr.On(http.MethodGet, "/v1/orders/*").ReplyJSON(200, order)
+//	mock.UseDefault(t, tr) //替换 httpclient.Default,测试结束后恢复
+//	... 被测代码 ...
+//	tr.AssertExpectations(t)
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/httpclient"
+)
+
+// ErrNoRoute 请求没有匹配的路由
+var ErrNoRoute = errors.New("mock: 没有匹配的路由")
+
+// Call 一次被记录的请求
+type Call struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Transport 可编程的 http.RoundTripper,并发安全。
+// 路由按注册顺序匹配,第一个匹配且未用完次数的路由生效
+type Transport struct {
+	mu     sync.Mutex
+	routes []*Route
+	calls  []Call
+}
+
+// NewTransport 创建 Transport
+func NewTransport() *Transport {
+	return &Transport{}
+}
+
+// On 注册路由。pattern 为路径(如 "/v1/orders")或完整 URL(含 scheme 时匹配 scheme+host+路径),
+// 支持 path.Match 通配符(如 "/v1/orders/*");method 为空匹配任意方法
+func (t *Transport) On(method, pattern string) *Route {
+	r := &Route{method: method, pattern: pattern, status: http.StatusOK, header: http.Header{}}
+	t.mu.Lock()
+	t.routes = append(t.routes, r)
+	t.mu.Unlock()
+	return r
+}
+
+// Client 创建使用该 Transport 的 httpclient 客户端
+func (t *Transport) Client(opts ...httpclient.Option) *httpclient.Client {
+	return httpclient.New(append([]httpclient.Option{httpclient.WithTransport(t)}, opts...)...)
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("mock: 读取请求 body 失败: %w", err)
+		}
+	}
+
+	t.mu.Lock()
+	t.calls = append(t.calls, Call{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone(), Body: body})
+	var matched *Route
+	for _, r := range t.routes {
+		if r.exhausted() || !r.match(req, body) {
+			continue
+		}
+		r.calls++
+		matched = r
+		break
+	}
+	t.mu.Unlock()
+
+	if matched == nil {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoRoute, req.Method, req.URL)
+	}
+	return matched.respond(req, body)
+}
+
+// Calls 返回所有请求(含未匹配的),按发生顺序
+func (t *Transport) Calls() []Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Call(nil), t.calls...)
+}
+
+// AssertExpectations 检查每个路由的调用次数:设置了 Times(n) 的必须恰好 n 次,其它至少 1 次
+func (t *Transport) AssertExpectations(tb testing.TB) {
+	tb.Helper()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, r := range t.routes {
+		switch {
+		case r.times > 0 && r.calls != r.times:
+			tb.Errorf("mock: %s 期望调用 %d 次,实际 %d 次", r, r.times, r.calls)
+		case r.times == 0 && r.calls == 0:
+			tb.Errorf("mock: %s 未被调用", r)
+		}
+	}
+}
+
+// UseDefault 用 rt 替换 httpclient.Default 的 Transport,测试结束后恢复
+func UseDefault(tb testing.TB, rt http.RoundTripper) {
+	tb.Helper()
+	old := httpclient.Default
+	httpclient.Default = httpclient.New(httpclient.WithTransport(rt))
+	tb.Cleanup(func() { httpclient.Default = old })
+}
+
+// Route 路由:匹配条件和响应
+type Route struct {
+	method  string
+	pattern string
+	query   map[string]string
+	headers map[string]string
+	bodyFn  func([]byte) bool
+
+	status int
+	header http.Header
+	body   []byte
+	err    error
+	fn     func(req *http.Request, body []byte) (*http.Response, error)
+	delay  time.Duration
+	times  int // 最多匹配次数,0 不限制
+	calls  int // 受 Transport.mu 保护
+}
+
+func (r *Route) String() string {
+	method := r.method
+	if method == "" {
+		method = "*"
+	}
+	return method + " " + r.pattern
+}
+
+// WithQuery 要求 query 参数 key 的值为 value
+func (r *Route) WithQuery(key, value string) *Route {
+	if r.query == nil {
+		r.query = make(map[string]string)
+	}
+	r.query[key] = value
+	return r
+}
+
+// WithHeader 要求请求 header key 的值为 value
+func (r *Route) WithHeader(key, value string) *Route {
+	if r.headers == nil {
+		r.headers = make(map[string]string)
+	}
+	r.headers[key] = value
+	return r
+}
+
+// WithBody 要求请求体包含 substr
+func (r *Route) WithBody(substr string) *Route {
+	return r.MatchBody(func(b []byte) bool { return bytes.Contains(b, []byte(substr)) })
+}
+
+// WithJSONBody 要求请求体与 v 的 JSON 语义相等(忽略字段顺序和空白)
+func (r *Route) WithJSONBody(v any) *Route {
+	want := normalizeJSON(v)
+	return r.MatchBody(func(b []byte) bool {
+		var got any
+		return json.Unmarshal(b, &got) == nil && reflect.DeepEqual(got, want)
+	})
+}
+
+// MatchBody 自定义请求体匹配
+func (r *Route) MatchBody(fn func(body []byte) bool) *Route {
+	r.bodyFn = fn
+	return r
+}
+
+// Reply 设置响应状态码和响应体
+func (r *Route) Reply(status int, body string) *Route {
+	r.status, r.body = status, []byte(body)
+	return r
+}
+
+// ReplyJSON 设置 JSON 响应
+func (r *Route) ReplyJSON(status int, v any) *Route {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("mock: 响应 JSON 序列化失败: %v", err))
+	}
+	r.status, r.body = status, data
+	r.header.Set("Content-Type", "application/json")
+	return r
+}
+
+// ReplyHeader 设置响应 header
+func (r *Route) ReplyHeader(key, value string) *Route {
+	r.header.Set(key, value)
+	return r
+}
+
+// ReplyError 请求返回 err(模拟网络错误)
+func (r *Route) ReplyError(err error) *Route {
+	r.err = err
+	return r
+}
+
+// ReplyFunc 由 fn 生成响应,body 为已读取的请求体
+func (r *Route) ReplyFunc(fn func(req *http.Request, body []byte) (*http.Response, error)) *Route {
+	r.fn = fn
+	return r
+}
+
+// Delay 响应前等待 d(请求 ctx 取消时提前返回),用于测试超时
+func (r *Route) Delay(d time.Duration) *Route {
+	r.delay = d
+	return r
+}
+
+// Times 最多匹配 n 次,用完后由后续路由匹配;AssertExpectations 要求恰好 n 次
+func (r *Route) Times(n int) *Route {
+	r.times = n
+	return r
+}
+
+// Once 等同 Times(1)
+func (r *Route) Once() *Route {
+	return r.Times(1)
+}
+
+func (r *Route) exhausted() bool {
+	return r.times > 0 && r.calls >= r.times
+}
+
+func (r *Route) match(req *http.Request, body []byte) bool {
+	if r.method != "" && !strings.EqualFold(r.method, req.Method) {
+		return false
+	}
+	target := req.URL.Path
+	if strings.Contains(r.pattern, "://") {
+		target = req.URL.Scheme + "://" + req.URL.Host + req.URL.Path
+	}
+	if ok, _ := path.Match(r.pattern, target); !ok {
+		return false
+	}
+	q := req.URL.Query()
+	for k, v := range r.query {
+		if q.Get(k) != v {
+			return false
+		}
+	}
+	for k, v := range r.headers {
+		if req.Header.Get(k) != v {
+			return false
+		}
+	}
+	return r.bodyFn == nil || r.bodyFn(body)
+}
+
+func (r *Route) respond(req *http.Request, body []byte) (*http.Response, error) {
+	if r.delay > 0 {
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(r.delay):
+		}
+	}
+	if r.fn != nil {
+		return r.fn(req, body)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return newResponse(req, r.status, r.header.Clone(), r.body), nil
+}
+
+func newResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// normalizeJSON 把 v 转为 json.Unmarshal 得到的通用结构,便于比较
+func normalizeJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("mock: JSON 序列化失败: %v", err))
+	}
+	var out any
+	_ = json.Unmarshal(data, &out)
+	return out
+}
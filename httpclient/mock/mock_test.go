@@ -0,0 +1,153 @@
+package mock
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/context"
+	"github.com/aichy126/igo/httpclient"
+)
+
+func TestTransport(t *testing.T) {
+	tr := NewTransport()
+	tr.On(http.MethodGet, "/v1/orders/*").WithQuery("expand", "items").ReplyJSON(200, map[string]string{"id": "42"})
+	tr.On(http.MethodPost, "/v1/orders").WithJSONBody(map[string]any{"sku": "a", "n": 2}).Reply(201, `{"id":"43"}`).Once()
+	tr.On(http.MethodPost, "/v1/orders").Reply(409, "duplicate")
+	c := tr.Client(httpclient.WithBaseURL("https://api.example.com"))
+	ctx := context.Background()
+
+	var out struct{ ID string }
+	if err := c.GetJSON(ctx, "/v1/orders/42?expand=items", &out); err != nil || out.ID != "42" {
+		t.Fatalf("GetJSON = %+v, %v", out, err)
+	}
+	// JSON 请求体语义匹配,忽略字段顺序
+	if err := c.PostJSON(ctx, "/v1/orders", map[string]any{"n": 2, "sku": "a"}, &out); err != nil || out.ID != "43" {
+		t.Fatalf("PostJSON = %+v, %v", out, err)
+	}
+	// Once 用完后由后续路由匹配
+	if err := c.PostJSON(ctx, "/v1/orders", map[string]any{"n": 2, "sku": "a"}, nil); httpclient.StatusCode(err) != 409 {
+		t.Errorf("第二次 PostJSON error = %v, want 409", err)
+	}
+	if _, err := c.Get(ctx, "/v2/unknown"); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("未匹配 error = %v, want ErrNoRoute", err)
+	}
+
+	calls := tr.Calls()
+	if len(calls) != 4 || calls[1].Method != http.MethodPost || string(calls[1].Body) != `{"n":2,"sku":"a"}` {
+		t.Errorf("calls = %+v", calls)
+	}
+	tr.AssertExpectations(t)
+
+	// 未调用的路由应报告
+	unused := NewTransport()
+	unused.On(http.MethodGet, "/never")
+	rec := &recordTB{TB: t}
+	unused.AssertExpectations(rec)
+	if rec.errors != 1 {
+		t.Error("未调用的路由应使断言失败")
+	}
+}
+
+// recordTB 记录 Errorf 调用,不让外层测试失败
+type recordTB struct {
+	testing.TB
+	errors int
+}
+
+func (r *recordTB) Errorf(string, ...any) { r.errors++ }
+
+func TestTransportErrorAndDelay(t *testing.T) {
+	tr := NewTransport()
+	boom := errors.New("connection reset")
+	tr.On("", "/err").ReplyError(boom)
+	tr.On("", "/slow").Delay(time.Second)
+	c := tr.Client(httpclient.WithTimeout(20 * time.Millisecond))
+
+	if _, err := c.Get(context.Background(), "http://x/err"); !errors.Is(err, boom) || !httpclient.IsConnectionError(err) {
+		t.Errorf("ReplyError error = %v", err)
+	}
+	if _, err := c.Get(context.Background(), "http://x/slow"); !httpclient.IsTimeout(err) {
+		t.Errorf("Delay 超时 error = %v", err)
+	}
+}
+
+func TestUseDefault(t *testing.T) {
+	tr := NewTransport()
+	tr.On(http.MethodGet, "https://api.example.com/ping").Reply(200, "pong")
+	old := httpclient.Default
+	t.Run("replace", func(t *testing.T) {
+		UseDefault(t, tr)
+		resp, err := httpclient.Get(context.Background(), "https://api.example.com/ping")
+		if err != nil || resp.String() != "pong" {
+			t.Errorf("resp = %v, err = %v", resp, err)
+		}
+	})
+	if httpclient.Default != old {
+		t.Error("测试结束后应恢复 httpclient.Default")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("X-Hit", "1")
+		if r.URL.Path == "/bin" {
+			w.Write([]byte{0xff, 0x00, 0xfe})
+			return
+		}
+		w.Write([]byte(r.URL.Path + ":" + r.Header.Get("Authorization")))
+	}))
+	defer srv.Close()
+	file := filepath.Join(t.TempDir(), "cassettes", "api.json")
+	ctx := context.Background()
+
+	rec, err := NewRecorder(file, ModeAuto, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Recording() {
+		t.Fatal("文件不存在时应录制")
+	}
+	c := httpclient.New(httpclient.WithTransport(rec), httpclient.WithHeader("Authorization", "Bearer secret"))
+	if _, err := c.Get(ctx, srv.URL+"/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, srv.URL+"/bin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if h := rec.Interactions()[0].Request.Header.Get("Authorization"); h != "" {
+		t.Errorf("录制文件不应包含 Authorization: %q", h)
+	}
+	srv.Close()
+
+	// 离线回放
+	replay, err := NewRecorder(file, ModeAuto, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Recording() {
+		t.Fatal("文件存在时应回放")
+	}
+	c = httpclient.New(httpclient.WithTransport(replay))
+	resp, err := c.Get(ctx, srv.URL+"/a")
+	if err != nil || resp.String() != "/a:Bearer secret" || resp.Header.Get("X-Hit") != "1" {
+		t.Errorf("回放 resp = %v, err = %v", resp, err)
+	}
+	if bin, err := c.GetBytes(ctx, srv.URL+"/bin"); err != nil || string(bin) != "\xff\x00\xfe" {
+		t.Errorf("二进制回放 = %q, %v", bin, err)
+	}
+	if _, err := c.Get(ctx, srv.URL+"/missing"); !errors.Is(err, ErrNoRoute) {
+		t.Errorf("未录制的请求 error = %v", err)
+	}
+	if hits != 2 {
+		t.Errorf("上游请求次数 = %d, want 2", hits)
+	}
+}
@@ -0,0 +1,209 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"unicode/utf8"
+)
+
+// Mode Recorder 的工作模式
+type Mode int
+
+const (
+	ModeReplay Mode = iota // 只回放,没有匹配的录制记录时返回错误(不访问网络)
+	ModeRecord             // 访问真实服务并录制,覆盖已有文件
+	ModeAuto               // 文件存在时回放,否则录制
+)
+
+// 录制时不写入文件的请求 header(避免凭据进入仓库)
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// Interaction 一次录制的请求与响应
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest 录制的请求
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	Base64 bool        `json:"base64,omitempty"` // Body 不是 UTF-8 文本时以 base64 保存
+}
+
+// RecordedResponse 录制的响应
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+	Base64     bool        `json:"base64,omitempty"`
+}
+
+// Recorder 录制/回放的 http.RoundTripper(cassette)。
+// 回放时按 method + URL + 请求体匹配,同样的请求按录制顺序依次返回,用完后重复返回最后一条
+//
+//	rec, err := mock.NewRecorder("testdata/payment.json", mock.ModeAuto, nil)
+//	client := httpclient.New(httpclient.WithTransport(rec))
+//	defer rec.Save()
+type Recorder struct {
+	file string
+	mode Mode
+	real http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder 创建 Recorder;real 为录制时使用的真实 Transport,nil 使用 http.DefaultTransport
+func NewRecorder(file string, mode Mode, real http.RoundTripper) (*Recorder, error) {
+	if real == nil {
+		real = http.DefaultTransport
+	}
+	r := &Recorder{file: file, mode: mode, real: real}
+	if mode == ModeAuto {
+		if _, err := os.Stat(file); err == nil {
+			r.mode = ModeReplay
+		} else {
+			r.mode = ModeRecord
+		}
+	}
+	if r.mode == ModeReplay {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("mock: 读取录制文件失败: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.interactions); err != nil {
+			return nil, fmt.Errorf("mock: 解析录制文件 %s 失败: %w", file, err)
+		}
+		r.used = make([]bool, len(r.interactions))
+	}
+	return r, nil
+}
+
+// Recording 是否处于录制模式
+func (r *Recorder) Recording() bool {
+	return r.mode == ModeRecord
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("mock: 读取请求 body 失败: %w", err)
+		}
+	}
+	if r.mode == ModeReplay {
+		return r.replay(req, body)
+	}
+	return r.record(req, body)
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := -1
+	for i, it := range r.interactions {
+		if it.Request.Method != req.Method || it.Request.URL != req.URL.String() || !bytes.Equal(decodeBody(it.Request.Body, it.Request.Base64), body) {
+			continue
+		}
+		last = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if last < 0 {
+		return nil, fmt.Errorf("%w: 录制文件 %s 中没有 %s %s", ErrNoRoute, r.file, req.Method, req.URL)
+	}
+	r.used[last] = true
+	resp := r.interactions[last].Response
+	return newResponse(req, resp.StatusCode, resp.Header.Clone(), decodeBody(resp.Body, resp.Base64)), nil
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	out.ContentLength = int64(len(body))
+	resp, err := r.real.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("mock: 读取响应 body 失败: %w", err)
+	}
+
+	header := req.Header.Clone()
+	for _, k := range sensitiveHeaders {
+		header.Del(k)
+	}
+	it := Interaction{
+		Request:  RecordedRequest{Method: req.Method, URL: req.URL.String(), Header: header},
+		Response: RecordedResponse{StatusCode: resp.StatusCode, Header: resp.Header.Clone()},
+	}
+	it.Request.Body, it.Request.Base64 = encodeBody(body)
+	it.Response.Body, it.Response.Base64 = encodeBody(data)
+	r.mu.Lock()
+	r.interactions = append(r.interactions, it)
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// Interactions 返回已录制(或加载)的记录
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.interactions)
+}
+
+// Save 录制模式下把记录写入文件,回放模式下无操作
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("mock: 序列化录制记录失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.file), 0o755); err != nil {
+		return fmt.Errorf("mock: 创建录制目录失败: %w", err)
+	}
+	if err := os.WriteFile(r.file, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("mock: 写入录制文件失败: %w", err)
+	}
+	return nil
+}
+
+func encodeBody(b []byte) (string, bool) {
+	if utf8.Valid(b) {
+		return string(b), false
+	}
+	return base64.StdEncoding.EncodeToString(b), true
+}
+
+func decodeBody(s string, b64 bool) []byte {
+	if !b64 {
+		return []byte(s)
+	}
+	data, _ := base64.StdEncoding.DecodeString(s)
+	return data
+}
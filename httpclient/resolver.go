@@ -0,0 +1,136 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aichy126/igo/log"
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// ServiceHostPrefix 服务地址的 host 前缀:http://svc.payment/v1/orders 中的 payment 为服务名
+const ServiceHostPrefix = "svc."
+
+// Resolver 把服务名解析为可用实例地址(host:port)列表
+type Resolver interface {
+	Resolve(ctx context.Context, service string) ([]string, error)
+}
+
+// StaticResolver 固定的服务实例列表,适合测试和没有注册中心的环境
+//
+//	httpclient.StaticResolver{"payment": {"10.0.0.1:8080", "10.0.0.2:8080"}}
+type StaticResolver map[string][]string
+
+// Resolve 返回服务的固定实例列表
+func (r StaticResolver) Resolve(_ context.Context, service string) ([]string, error) {
+	addrs, ok := r[service]
+	if !ok {
+		return nil, fmt.Errorf("服务 %s 未配置实例", service)
+	}
+	return addrs, nil
+}
+
+// DefaultConsulRefresh consul 解析结果的默认缓存时间
+const DefaultConsulRefresh = 10 * time.Second
+
+// ConsulResolver 通过 consul 健康检查接口解析服务,只返回检查通过的实例。
+// 解析结果按服务缓存,过期后在请求时刷新;刷新失败继续使用旧结果,避免 consul 抖动影响调用
+type ConsulResolver struct {
+	client     *consulapi.Client
+	tag        string
+	datacenter string
+	refresh    time.Duration
+
+	mu       sync.Mutex
+	services map[string]*consulEntry
+}
+
+type consulEntry struct {
+	mu      sync.Mutex // 同一服务同时只有一个刷新请求
+	addrs   []string
+	expires time.Time
+}
+
+// ConsulOption ConsulResolver 配置项
+type ConsulOption func(*ConsulResolver)
+
+// WithConsulTag 只解析带该 tag 的实例
+func WithConsulTag(tag string) ConsulOption {
+	return func(r *ConsulResolver) { r.tag = tag }
+}
+
+// WithConsulDatacenter 指定数据中心,默认为 agent 所在数据中心
+func WithConsulDatacenter(dc string) ConsulOption {
+	return func(r *ConsulResolver) { r.datacenter = dc }
+}
+
+// WithConsulRefresh 设置解析结果缓存时间(默认 10s)
+func WithConsulRefresh(d time.Duration) ConsulOption {
+	return func(r *ConsulResolver) { r.refresh = d }
+}
+
+// NewConsulResolver 创建 consul 解析器,address 为 agent 地址(如 "127.0.0.1:8500"),为空使用 consul 默认配置
+func NewConsulResolver(address string, opts ...ConsulOption) (*ConsulResolver, error) {
+	cfg := consulapi.DefaultConfig()
+	if address != "" {
+		cfg.Address = address
+	}
+	client, err := consulapi.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("创建 consul 客户端失败: %w", err)
+	}
+	r := &ConsulResolver{client: client, refresh: DefaultConsulRefresh, services: make(map[string]*consulEntry)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Resolve 返回服务检查通过的实例地址
+func (r *ConsulResolver) Resolve(ctx context.Context, service string) ([]string, error) {
+	r.mu.Lock()
+	e, ok := r.services[service]
+	if !ok {
+		e = &consulEntry{}
+		r.services[service] = e
+	}
+	r.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.addrs != nil && time.Now().Before(e.expires) {
+		return e.addrs, nil
+	}
+	addrs, err := r.query(ctx, service)
+	if err != nil {
+		if e.addrs != nil {
+			log.Warn("consul 解析服务失败,继续使用旧的实例列表", log.String("service", service), log.Any("error", err))
+			e.expires = time.Now().Add(r.refresh)
+			return e.addrs, nil
+		}
+		return nil, err
+	}
+	e.addrs, e.expires = addrs, time.Now().Add(r.refresh)
+	return addrs, nil
+}
+
+func (r *ConsulResolver) query(ctx context.Context, service string) ([]string, error) {
+	q := (&consulapi.QueryOptions{Datacenter: r.datacenter}).WithContext(ctx)
+	entries, _, err := r.client.Health().Service(service, r.tag, true, q)
+	if err != nil {
+		return nil, fmt.Errorf("consul 解析服务 %s 失败: %w", service, err)
+	}
+	addrs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address // 服务未单独注册地址时使用节点地址
+		}
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)))
+	}
+	return addrs, nil
+}
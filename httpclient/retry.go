@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// DefaultIdempotencyHeader 默认幂等键 header,POST/PATCH 请求带上它才会重试
+const DefaultIdempotencyHeader = "Idempotency-Key"
+
+// RetryPolicy 重试策略
+type RetryPolicy struct {
+	MaxRetries int           // 最大重试次数,0 不重试
+	BaseDelay  time.Duration // 首次重试的等待时间,之后按 2 的幂增长
+	MaxDelay   time.Duration // 单次等待上限,Retry-After 超过该值时不再重试
+	Jitter     float64       // 随机抖动比例 [0,1]:实际等待时间在 [delay*(1-Jitter), delay] 之间,避免重试风暴
+
+	RetryStatuses      []int  // 需要重试的 HTTP 状态码
+	RetryNonIdempotent bool   // 是否重试 POST/PATCH 等非幂等请求(默认只在带幂等键时重试)
+	IdempotencyHeader  string // 幂等键 header,为空使用 DefaultIdempotencyHeader
+}
+
+// DefaultRetryPolicy 默认重试策略:重试 2 次,100ms 起指数退避(上限 5s,20% 抖动),
+// 重试网络错误和 429/502/503/504,遵循 Retry-After
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:    2,
+		BaseDelay:     100 * time.Millisecond,
+		MaxDelay:      5 * time.Second,
+		Jitter:        0.2,
+		RetryStatuses: []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout},
+	}
+}
+
+// WithRetryPolicy 设置客户端默认的重试策略,可通过 WithReqRetry 按请求覆盖
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// canRetry 请求是否允许重试:幂等方法,或带幂等键,或策略允许重试非幂等请求
+func (p RetryPolicy) canRetry(method string, header http.Header) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	if p.RetryNonIdempotent {
+		return true
+	}
+	name := p.IdempotencyHeader
+	if name == "" {
+		name = DefaultIdempotencyHeader
+	}
+	return header.Get(name) != ""
+}
+
+// retryStatus 状态码是否需要重试
+func (p RetryPolicy) retryStatus(code int) bool {
+	return slices.Contains(p.RetryStatuses, code)
+}
+
+// backoff 第 n 次重试(从 1 开始)的等待时间
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && (p.MaxDelay <= 0 || d < p.MaxDelay); i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 && d > 0 {
+		d -= time.Duration(rand.Float64() * min(p.Jitter, 1) * float64(d))
+	}
+	return d
+}
+
+// retryAfter 解析 Retry-After 响应头(秒数或 HTTP 日期),没有或无法解析时返回 false
+func retryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
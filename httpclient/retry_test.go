@@ -0,0 +1,121 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer 前 n 次返回 status,之后返回 200
+func flakyServer(t *testing.T, n int32, status int, header http.Header) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= n {
+			for k, vs := range header {
+				w.Header()[k] = vs
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func fastPolicy() RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.BaseDelay = time.Millisecond
+	return p
+}
+
+func TestRetryStatus(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable, nil)
+	resp, err := New(WithRetryPolicy(fastPolicy())).Get(t.Context(), srv.URL)
+	if err != nil || resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Fatalf("resp=%v err=%v calls=%d", resp, err, calls.Load())
+	}
+
+	// 重试次数用完返回最后一次响应
+	srv, calls = flakyServer(t, 10, http.StatusBadGateway, nil)
+	resp, err = New(WithRetryPolicy(fastPolicy())).Get(t.Context(), srv.URL)
+	if err != nil || resp.StatusCode != http.StatusBadGateway || calls.Load() != 3 {
+		t.Fatalf("resp=%v err=%v calls=%d", resp, err, calls.Load())
+	}
+
+	// 不在列表中的状态码不重试
+	srv, calls = flakyServer(t, 1, http.StatusInternalServerError, nil)
+	if resp, _ := New(WithRetryPolicy(fastPolicy())).Get(t.Context(), srv.URL); resp.StatusCode != 500 || calls.Load() != 1 {
+		t.Errorf("500 不应重试, calls=%d", calls.Load())
+	}
+}
+
+func TestRetryIdempotency(t *testing.T) {
+	client := New(WithRetryPolicy(fastPolicy()))
+
+	srv, calls := flakyServer(t, 1, http.StatusServiceUnavailable, nil)
+	resp, _ := client.Post(t.Context(), srv.URL, "text/plain", strings.NewReader("x"))
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("无幂等键的 POST 不应重试, calls=%d", calls.Load())
+	}
+
+	srv, calls = flakyServer(t, 1, http.StatusServiceUnavailable, nil)
+	resp, _ = client.Post(t.Context(), srv.URL, "text/plain", strings.NewReader("x"), WithIdempotencyKey("order-1"))
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("带幂等键的 POST 应重试, calls=%d", calls.Load())
+	}
+
+	srv, calls = flakyServer(t, 1, http.StatusServiceUnavailable, nil)
+	p := fastPolicy()
+	p.RetryNonIdempotent = true
+	resp, _ = client.Post(t.Context(), srv.URL, "text/plain", strings.NewReader("x"), WithReqRetry(p))
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("RetryNonIdempotent 时 POST 应重试, calls=%d", calls.Load())
+	}
+}
+
+func TestRetryOverride(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusServiceUnavailable, nil)
+	resp, _ := New(WithRetryPolicy(fastPolicy())).Get(t.Context(), srv.URL, WithReqRetry(RetryPolicy{}))
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("请求级覆盖为不重试, calls=%d", calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+	start := time.Now()
+	resp, _ := New(WithRetryPolicy(fastPolicy())).Get(t.Context(), srv.URL)
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 || time.Since(start) < time.Second {
+		t.Errorf("应按 Retry-After 等待后重试: status=%d calls=%d cost=%v", resp.StatusCode, calls.Load(), time.Since(start))
+	}
+
+	// Retry-After 超过 MaxDelay 不重试
+	srv, calls = flakyServer(t, 1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"60"}})
+	resp, _ = New(WithRetryPolicy(fastPolicy())).Get(t.Context(), srv.URL)
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("Retry-After 过长不应重试, calls=%d", calls.Load())
+	}
+
+	now := time.Now()
+	if d, ok := retryAfter(http.Header{"Retry-After": {now.Add(3 * time.Second).UTC().Format(http.TimeFormat)}}, now); !ok || d < 2*time.Second || d > 3*time.Second {
+		t.Errorf("HTTP 日期格式 Retry-After = %v, %v", d, ok)
+	}
+	if _, ok := retryAfter(http.Header{"Retry-After": {"soon"}}, now); ok {
+		t.Error("无法解析的 Retry-After 应忽略")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: 0.5}
+	for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		for range 20 {
+			if d := p.backoff(n); d > want || d < want/2 {
+				t.Fatalf("backoff(%d) = %v, want [%v, %v]", n, d, want/2, want)
+			}
+		}
+	}
+}
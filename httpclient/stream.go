@@ -0,0 +1,336 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aichy126/igo/log"
+)
+
+// ErrResponseTooLarge 响应体超过 WithMaxResponseSize 的限制
+var ErrResponseTooLarge = errors.New("httpclient 响应体超过大小限制")
+
+// readBody 读取响应体,max > 0 时超过 max 字节返回 ErrResponseTooLarge
+func readBody(r io.Reader, max int64) ([]byte, error) {
+	if max <= 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("读取响应 body 失败: %w", err)
+		}
+		return data, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取响应 body 失败: %w", err)
+	}
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("%w: 超过 %d 字节", ErrResponseTooLarge, max)
+	}
+	return data, nil
+}
+
+// StreamResponse 流式响应,调用方读取完毕后必须 Close
+type StreamResponse struct {
+	StatusCode    int
+	Header        http.Header
+	ContentLength int64 // -1 表示未知
+	Body          io.ReadCloser
+}
+
+// OK 状态码是否为 2xx
+func (r *StreamResponse) OK() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Close 关闭响应体
+func (r *StreamResponse) Close() error {
+	return r.Body.Close()
+}
+
+// Stream 发起请求并返回未读取的响应体,body 也以流的形式发送(不缓冲)。
+// 不受 WithTimeout 和 WithMaxResponseSize 限制,超时由 ctx 控制;body 无法重放,因此不重试
+//
+//	resp, err := client.Stream(ctx, http.MethodGet, url, nil)
+//	if err != nil { return err }
+//	defer resp.Close()
+//	io.Copy(dst, resp.Body)
+func (c *Client) Stream(ctx context.Context, method, rawurl string, body io.Reader, opts ...ReqOption) (*StreamResponse, error) {
+	ro := newRequestOptions(nil, opts)
+	rawurl = c.resolveURL(rawurl)
+	req, err := http.NewRequestWithContext(ctx, method, rawurl, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.mergeHeader(ctx, ro.header)
+	c.setDeadline(req, 0)
+
+	var raw *http.Response
+	_, err = c.guard(ctx, req.URL.Host, func() (*Response, error) {
+		x := &exchange{req: req, attempt: 1, template: ro.pathTemplate}
+		start := time.Now()
+		raw, err = c.send(c.streamClient(), req)
+		// 响应体由调用方读取,访问日志中的响应字节数取 Content-Length
+		x.finish(start, raw, nil, err)
+		c.record(x)
+		if err != nil {
+			if c.debug {
+				log.Error("httpclient 请求失败", log.String("method", method), log.String("url", rawurl), log.Any("error", err))
+			}
+			return nil, err
+		}
+		if c.debug {
+			log.Debug("httpclient stream",
+				log.String("method", method),
+				log.String("url", rawurl),
+				log.Int("status", raw.StatusCode),
+				log.Duration("cost", x.elapsed),
+			)
+		}
+		// 熔断只根据状态码判断
+		return &Response{StatusCode: raw.StatusCode, Header: raw.Header}, nil
+	})
+	if err != nil {
+		return nil, newRequestError(method, rawurl, 1, err)
+	}
+	return &StreamResponse{
+		StatusCode:    raw.StatusCode,
+		Header:        raw.Header,
+		ContentLength: raw.ContentLength,
+		Body:          raw.Body,
+	}, nil
+}
+
+// streamClient 流式请求使用的 http.Client:共用连接池,但不设置整体超时(整体超时包含读取 body 的时间)
+func (c *Client) streamClient() *http.Client {
+	return &http.Client{
+		Transport:     c.hc.Transport,
+		CheckRedirect: c.hc.CheckRedirect,
+		Jar:           c.hc.Jar,
+	}
+}
+
+// Download 下载文件到 path,返回文件大小。
+// 先写入 path + ".part",完成后重命名;.part 已存在时通过 Range 请求断点续传。
+// 首次下载时把 ETag/Last-Modified 和文件大小记录在 path + ".part.meta",续传时以 If-Range 发送,
+// 远端文件已变化(返回 200、总大小不一致或 416 的大小与 .part 不符)时从头重新下载
+func (c *Client) Download(ctx context.Context, rawurl, path string, opts ...ReqOption) (int64, error) {
+	part := path + ".part"
+	var offset int64
+	if fi, err := os.Stat(part); err == nil {
+		offset = fi.Size()
+	}
+	meta := readPartMeta(part)
+	reqOpts := opts
+	if offset > 0 {
+		reqOpts = append(slices.Clip(opts), WithReqHeader("Range", fmt.Sprintf("bytes=%d-", offset)))
+		if meta.validator != "" {
+			reqOpts = append(reqOpts, WithReqHeader("If-Range", meta.validator))
+		}
+	}
+
+	resp, err := c.Stream(ctx, http.MethodGet, rawurl, nil, reqOpts...)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Close()
+
+	flag := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	switch {
+	case offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return 0, fmt.Errorf("续传失败:Content-Range %q 与本地文件大小 %d 不一致", resp.Header.Get("Content-Range"), offset)
+		}
+		if meta.total >= 0 && total >= 0 && total != meta.total {
+			resp.Close()
+			return c.restartDownload(ctx, rawurl, path, fmt.Sprintf("文件大小由 %d 变为 %d", meta.total, total), opts)
+		}
+		flag = os.O_WRONLY | os.O_APPEND
+	case offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// 远端大小等于 .part 时 .part 已是完整文件,否则远端文件已变化
+		_, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || total != offset || (meta.total >= 0 && meta.total != offset) {
+			resp.Close()
+			return c.restartDownload(ctx, rawurl, path, fmt.Sprintf("416 Content-Range %q 与本地文件大小 %d 不一致", resp.Header.Get("Content-Range"), offset), opts)
+		}
+		if err := os.Rename(part, path); err != nil {
+			return 0, fmt.Errorf("重命名下载文件失败: %w", err)
+		}
+		os.Remove(part + ".meta")
+		return offset, nil
+	case resp.OK():
+		offset = 0
+		if err := writePartMeta(part, resp); err != nil {
+			return 0, err
+		}
+	default:
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, bodySnippetSize))
+		return 0, &Error{
+			Kind:       KindStatus,
+			Method:     http.MethodGet,
+			URL:        c.resolveURL(rawurl),
+			StatusCode: resp.StatusCode,
+			Body:       string(data),
+			Attempts:   1,
+		}
+	}
+
+	f, err := os.OpenFile(part, flag, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("打开下载文件失败: %w", err)
+	}
+	n, err := io.Copy(f, resp.Body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// 保留 .part,下次调用从断点继续
+		return offset + n, fmt.Errorf("下载中断(已保存 %d 字节,可重新调用续传): %w", offset+n, err)
+	}
+	if err := os.Rename(part, path); err != nil {
+		return 0, fmt.Errorf("重命名下载文件失败: %w", err)
+	}
+	os.Remove(part + ".meta")
+	return offset + n, nil
+}
+
+// restartDownload 远端文件已变化,删除 .part 后从头下载
+func (c *Client) restartDownload(ctx context.Context, rawurl, path, reason string, opts []ReqOption) (int64, error) {
+	log.Warn("httpclient 远端文件已变化,重新下载", log.String("url", c.resolveURL(rawurl)), log.String("reason", reason))
+	part := path + ".part"
+	if err := os.Remove(part); err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("删除下载文件失败: %w", err)
+	}
+	os.Remove(part + ".meta")
+	return c.Download(ctx, rawurl, path, opts...)
+}
+
+// partMeta .part 对应的远端文件信息
+type partMeta struct {
+	validator string // If-Range 的值:强 ETag,没有时为 Last-Modified
+	total     int64  // 文件大小,-1 表示未知
+}
+
+// readPartMeta 读取 part + ".meta";不存在或格式不对时返回未知
+func readPartMeta(part string) partMeta {
+	meta := partMeta{total: -1}
+	data, err := os.ReadFile(part + ".meta")
+	if err != nil {
+		return meta
+	}
+	validator, size, _ := strings.Cut(strings.TrimRight(string(data), "\n"), "\n")
+	meta.validator = validator
+	if total, err := strconv.ParseInt(size, 10, 64); err == nil {
+		meta.total = total
+	}
+	return meta
+}
+
+// writePartMeta 记录完整响应的 ETag/Last-Modified 和大小;If-Range 不能使用弱 ETag
+func writePartMeta(part string, resp *StreamResponse) error {
+	validator := resp.Header.Get("ETag")
+	if validator == "" || strings.HasPrefix(validator, "W/") {
+		validator = resp.Header.Get("Last-Modified")
+	}
+	data := fmt.Sprintf("%s\n%d\n", validator, resp.ContentLength)
+	if err := os.WriteFile(part+".meta", []byte(data), 0o644); err != nil {
+		return fmt.Errorf("写入下载信息失败: %w", err)
+	}
+	return nil
+}
+
+// parseContentRange 解析 "bytes start-end/total" 或 "bytes */total",total 为 * 时返回 -1
+func parseContentRange(v string) (start, total int64, ok bool) {
+	v, ok = strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, size, ok := strings.Cut(v, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		var err error
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	if rng == "*" {
+		return 0, total, true
+	}
+	s, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(s, 10, 64)
+	return start, total, err == nil
+}
+
+// MultipartFile multipart 上传的文件
+type MultipartFile struct {
+	Field       string    // 表单字段名
+	FileName    string    // 文件名
+	ContentType string    // 为空时使用 application/octet-stream
+	Reader      io.Reader // 文件内容,边读边发送,不会整体读入内存
+}
+
+// PostMultipart 以 multipart/form-data 上传表单字段和文件,请求体流式发送(不重试),响应按缓冲式 API 读取
+func (c *Client) PostMultipart(ctx context.Context, rawurl string, fields url.Values, files []MultipartFile, opts ...ReqOption) (*Response, error) {
+	pr, pw := io.Pipe()
+	defer pr.Close() // 上游提前返回时让写 goroutine 退出
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipart(mw, fields, files))
+	}()
+
+	opts = append([]ReqOption{WithReqHeader("Content-Type", mw.FormDataContentType())}, opts...)
+	resp, err := c.Stream(ctx, http.MethodPost, rawurl, pr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	data, err := readBody(resp.Body, c.maxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data, method: http.MethodPost, url: c.resolveURL(rawurl), attempts: 1}, nil
+}
+
+func writeMultipart(mw *multipart.Writer, fields url.Values, files []MultipartFile) error {
+	for k, vs := range fields {
+		for _, v := range vs {
+			if err := mw.WriteField(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	for _, f := range files {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", multipart.FileContentDisposition(f.Field, f.FileName))
+		ct := f.ContentType
+		if ct == "" {
+			ct = "application/octet-stream"
+		}
+		h.Set("Content-Type", ct)
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(w, f.Reader); err != nil {
+			return fmt.Errorf("读取上传文件 %s 失败: %w", f.FileName, err)
+		}
+	}
+	return mw.Close()
+}
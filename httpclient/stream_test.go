@@ -0,0 +1,223 @@
+package httpclient
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		for i := range 3 {
+			_, _ = w.Write(append(body, byte('0'+i)))
+			w.(http.Flusher).Flush()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	// 整体超时不作用于流式请求
+	c := New(WithTimeout(80 * time.Millisecond))
+	resp, err := c.Stream(t.Context(), http.MethodPost, srv.URL, strings.NewReader("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil || string(data) != "x0x1x2" {
+		t.Errorf("Stream body = %q, %v", data, err)
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("a"), 100))
+	}))
+	defer srv.Close()
+
+	if _, err := New(WithMaxResponseSize(99)).Get(t.Context(), srv.URL); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("err = %v, want ErrResponseTooLarge", err)
+	}
+	if resp, err := New(WithMaxResponseSize(100)).Get(t.Context(), srv.URL); err != nil || len(resp.Body) != 100 {
+		t.Errorf("恰好等于上限应成功: %v", err)
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	var ranges atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges.Store(r.Header.Get("Range"))
+		if r.URL.Path == "/norange" {
+			_, _ = w.Write(content)
+			return
+		}
+		http.ServeContent(w, r, "f", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	c := New()
+
+	// 全新下载
+	path := filepath.Join(dir, "a")
+	if n, err := c.Download(t.Context(), srv.URL, path); err != nil || n != int64(len(content)) {
+		t.Fatalf("Download = %d, %v", n, err)
+	}
+	assertFile(t, path, content)
+
+	// 从 .part 续传
+	path = filepath.Join(dir, "b")
+	_ = os.WriteFile(path+".part", content[:3000], 0o644)
+	if n, err := c.Download(t.Context(), srv.URL, path); err != nil || n != int64(len(content)) {
+		t.Fatalf("续传 Download = %d, %v", n, err)
+	}
+	if got := ranges.Load(); got != "bytes=3000-" {
+		t.Errorf("Range = %v", got)
+	}
+	assertFile(t, path, content)
+	if _, err := os.Stat(path + ".part"); !os.IsNotExist(err) {
+		t.Error("完成后 .part 应被重命名")
+	}
+
+	// .part 已完整(416)
+	path = filepath.Join(dir, "c")
+	_ = os.WriteFile(path+".part", content, 0o644)
+	if n, err := c.Download(t.Context(), srv.URL, path); err != nil || n != int64(len(content)) {
+		t.Fatalf("416 Download = %d, %v", n, err)
+	}
+	assertFile(t, path, content)
+
+	// 服务端不支持 Range,重新下载
+	path = filepath.Join(dir, "d")
+	_ = os.WriteFile(path+".part", []byte("garbage"), 0o644)
+	if _, err := c.Download(t.Context(), srv.URL+"/norange", path); err != nil {
+		t.Fatal(err)
+	}
+	assertFile(t, path, content)
+}
+
+// TestDownloadRemoteChanged 远端文件在两次下载之间变化时不能把新旧内容拼接在一起
+func TestDownloadRemoteChanged(t *testing.T) {
+	v1 := bytes.Repeat([]byte("a"), 10000)
+	var (
+		body  atomic.Pointer[[]byte]
+		etag  atomic.Value
+		abort atomic.Bool
+		ifRng atomic.Value
+	)
+	body.Store(&v1)
+	etag.Store("")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifRng.Store(r.Header.Get("If-Range"))
+		content := *body.Load()
+		if tag := etag.Load().(string); tag != "" {
+			w.Header().Set("ETag", tag)
+		}
+		if abort.Load() {
+			// 只发送一半后断开,留下 .part
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "f", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+	c := New()
+	dir := t.TempDir()
+
+	interrupted := func(path string) {
+		t.Helper()
+		abort.Store(true)
+		defer abort.Store(false)
+		if _, err := c.Download(t.Context(), srv.URL, path); err == nil {
+			t.Fatal("中断的下载应返回错误")
+		}
+		if _, err := os.Stat(path + ".part"); err != nil {
+			t.Fatalf("中断后应保留 .part: %v", err)
+		}
+	}
+
+	// ETag 变化:If-Range 不匹配,服务端返回完整的新文件
+	etag.Store(`"v1"`)
+	path := filepath.Join(dir, "etag")
+	interrupted(path)
+	v2 := bytes.Repeat([]byte("b"), 10000)
+	body.Store(&v2)
+	etag.Store(`"v2"`)
+	if n, err := c.Download(t.Context(), srv.URL, path); err != nil || n != int64(len(v2)) {
+		t.Fatalf("Download = %d, %v", n, err)
+	}
+	if got := ifRng.Load(); got != `"v1"` {
+		t.Errorf("If-Range = %v", got)
+	}
+	assertFile(t, path, v2)
+	if _, err := os.Stat(path + ".part.meta"); !os.IsNotExist(err) {
+		t.Error("完成后应删除 .part.meta")
+	}
+
+	// 没有 ETag/Last-Modified:按 206 中的总大小判断
+	body.Store(&v1)
+	etag.Store("")
+	path = filepath.Join(dir, "size")
+	interrupted(path)
+	v3 := bytes.Repeat([]byte("c"), 12000)
+	body.Store(&v3)
+	if n, err := c.Download(t.Context(), srv.URL, path); err != nil || n != int64(len(v3)) {
+		t.Fatalf("Download = %d, %v", n, err)
+	}
+	assertFile(t, path, v3)
+
+	// 416 但远端大小与 .part 不一致(远端变小):重新下载而不是把 .part 当作完整文件
+	path = filepath.Join(dir, "shrunk")
+	_ = os.WriteFile(path+".part", bytes.Repeat([]byte("x"), 20000), 0o644)
+	if n, err := c.Download(t.Context(), srv.URL, path); err != nil || n != int64(len(v3)) {
+		t.Fatalf("Download = %d, %v", n, err)
+	}
+	assertFile(t, path, v3)
+}
+
+func assertFile(t *testing.T, path string, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Errorf("%s 内容不一致: len=%d err=%v", path, len(got), err)
+	}
+}
+
+func TestPostMultipart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, h, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		_, _ = io.WriteString(w, r.FormValue("name")+"|"+h.Filename+"|"+h.Header.Get("Content-Type")+"|"+string(data))
+	}))
+	defer srv.Close()
+
+	resp, err := New().PostMultipart(t.Context(), srv.URL,
+		url.Values{"name": {"igo"}},
+		[]MultipartFile{{Field: "file", FileName: "a.txt", ContentType: "text/plain", Reader: strings.NewReader("hello")}},
+	)
+	if err != nil || resp.String() != "igo|a.txt|text/plain|hello" {
+		t.Errorf("PostMultipart = %v, %v", resp, err)
+	}
+}
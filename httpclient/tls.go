@@ -0,0 +1,232 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aichy126/igo/log"
+)
+
+// DefaultTLSReloadInterval 证书文件变更检查的默认间隔
+const DefaultTLSReloadInterval = time.Minute
+
+// WithRootCAs 使用 pemPath 中的 CA 证书校验服务端(替代系统根证书),适合内部 CA 签发的服务证书。
+// 文件变更后自动重新加载,见 WithTLSReloadInterval
+func WithRootCAs(pemPath string) Option {
+	return func(c *Client) { c.tlsFiles().caFile = pemPath }
+}
+
+// WithClientCertificate 设置客户端证书(双向 TLS),certFile/keyFile 为 PEM 文件。
+// 文件变更后自动重新加载,证书轮换无需重启
+func WithClientCertificate(certFile, keyFile string) Option {
+	return func(c *Client) {
+		f := c.tlsFiles()
+		f.certFile, f.keyFile = certFile, keyFile
+	}
+}
+
+// WithTLSReloadInterval 设置证书文件变更检查间隔(默认 1 分钟),<= 0 表示只在创建时加载一次。
+// 检查在 TLS 握手时进行(按文件修改时间判断),不启动后台 goroutine
+func WithTLSReloadInterval(d time.Duration) Option {
+	return func(c *Client) { c.tlsFiles().interval = d }
+}
+
+// WithMinTLSVersion 设置最低 TLS 版本,如 tls.VersionTLS13(默认 TLS 1.2)
+func WithMinTLSVersion(version uint16) Option {
+	return func(c *Client) {
+		if cfg := c.tlsConfig(); cfg != nil {
+			cfg.MinVersion = version
+		}
+	}
+}
+
+// tlsConfig 返回默认 Transport 的 TLS 配置(不存在则创建);自定义 Transport 时返回 nil
+func (c *Client) tlsConfig() *tls.Config {
+	t, ok := c.hc.Transport.(*http.Transport)
+	if !ok {
+		return nil
+	}
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	return t.TLSClientConfig
+}
+
+func (c *Client) tlsFiles() *tlsFiles {
+	if c.tls == nil {
+		c.tls = &tlsFiles{interval: DefaultTLSReloadInterval}
+	}
+	return c.tls
+}
+
+// tlsFiles 从磁盘加载并按修改时间热重载的 CA 与客户端证书
+type tlsFiles struct {
+	caFile, certFile, keyFile string
+	interval                  time.Duration
+
+	mu      sync.Mutex
+	checked time.Time            // 上次检查时间
+	modTime map[string]time.Time // 上次加载时各文件的修改时间
+	roots   *x509.CertPool
+	cert    *tls.Certificate
+	err     error // 从未成功加载时的错误,握手时返回
+}
+
+// install 在所有 Option 生效后调用,把证书回调挂到 TLS 配置上
+func (f *tlsFiles) install(c *Client) {
+	cfg := c.tlsConfig()
+	if cfg == nil {
+		log.Warn("httpclient 使用了自定义 Transport,WithRootCAs/WithClientCertificate 不生效")
+		return
+	}
+	if (f.certFile == "") != (f.keyFile == "") {
+		f.err = errors.New("客户端证书和私钥必须同时配置")
+	} else if err := f.load(); err != nil {
+		// 创建时加载失败不中断:记录错误,握手时返回,文件修复后下次检查自动恢复
+		log.Error("httpclient 加载 TLS 证书失败", log.Any("error", err))
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+	if f.certFile != "" {
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			_, cert, err := f.current()
+			if err != nil {
+				return nil, err
+			}
+			return cert, nil
+		}
+	}
+	// CA 需要热重载,而 RootCAs 在握手时被直接读取无法并发替换:
+	// 保留内置的证书链和主机名校验,CA 变更时换用设置了新 RootCAs 的 Transport
+	if f.caFile != "" && !cfg.InsecureSkipVerify {
+		c.hc.Transport = &caTransport{files: f, base: c.hc.Transport.(*http.Transport)}
+	}
+}
+
+// caTransport 使用当前 CA 的 Transport:CA 文件变更后复制 base 并替换 RootCAs,旧 Transport 的空闲连接被关闭
+type caTransport struct {
+	files *tlsFiles
+	base  *http.Transport // 只作为模板,不直接发请求
+
+	mu    sync.Mutex
+	roots *x509.CertPool
+	cur   *http.Transport
+}
+
+func (t *caTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	roots, _, err := t.files.current()
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	if roots != t.roots {
+		old := t.cur
+		t.cur = t.base.Clone()
+		t.cur.TLSClientConfig.RootCAs = roots
+		t.roots = roots
+		if old != nil {
+			old.CloseIdleConnections()
+		}
+	}
+	cur := t.cur
+	t.mu.Unlock()
+	return cur.RoundTrip(req)
+}
+
+// CloseIdleConnections 供 http.Client.CloseIdleConnections 调用
+func (t *caTransport) CloseIdleConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cur != nil {
+		t.cur.CloseIdleConnections()
+	}
+}
+
+// current 返回当前证书,到了检查间隔时按修改时间重新加载
+func (f *tlsFiles) current() (*x509.CertPool, *tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.interval > 0 && time.Since(f.checked) >= f.interval {
+		if err := f.load(); err != nil {
+			// 重新加载失败继续使用旧证书,避免写文件的中间状态导致请求失败
+			log.Warn("httpclient 重新加载 TLS 证书失败,继续使用旧证书", log.Any("error", err))
+		}
+	}
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	return f.roots, f.cert, nil
+}
+
+// load 文件修改时间有变化时重新读取;调用方持有锁(install 时尚未并发)
+func (f *tlsFiles) load() error {
+	f.checked = time.Now()
+	modTime := make(map[string]time.Time, 3)
+	changed := f.modTime == nil
+	for _, name := range []string{f.caFile, f.certFile, f.keyFile} {
+		if name == "" {
+			continue
+		}
+		st, err := os.Stat(name)
+		if err != nil {
+			return f.fail(fmt.Errorf("读取证书文件失败: %w", err))
+		}
+		modTime[name] = st.ModTime()
+		if !st.ModTime().Equal(f.modTime[name]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	var roots *x509.CertPool
+	if f.caFile != "" {
+		pem, err := os.ReadFile(f.caFile)
+		if err != nil {
+			return f.fail(fmt.Errorf("读取 CA 文件失败: %w", err))
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return f.fail(fmt.Errorf("CA 文件 %s 中没有有效的 PEM 证书", f.caFile))
+		}
+	}
+	var cert *tls.Certificate
+	if f.certFile != "" {
+		c, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+		if err != nil {
+			return f.fail(fmt.Errorf("加载客户端证书失败: %w", err))
+		}
+		cert = &c
+	}
+	reload := f.modTime != nil
+	f.roots, f.cert, f.modTime, f.err = roots, cert, modTime, nil
+	if reload {
+		log.Info("httpclient TLS 证书已重新加载", log.String("ca", f.caFile), log.String("cert", f.certFile))
+	}
+	return nil
+}
+
+// fail 记录错误;已有可用证书时不覆盖
+func (f *tlsFiles) fail(err error) error {
+	if f.modTime == nil {
+		f.err = err
+	}
+	return err
+}
+
+// checkTLSFiles 校验证书文件能否加载,用于配置加载阶段提前暴露错误
+func checkTLSFiles(caFile, certFile, keyFile string) error {
+	if (certFile == "") != (keyFile == "") {
+		return errors.New("tls_cert_file 和 tls_key_file 必须同时配置")
+	}
+	f := &tlsFiles{caFile: caFile, certFile: certFile, keyFile: keyFile}
+	return f.load()
+}
@@ -0,0 +1,224 @@
+package httpclient
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/context"
+)
+
+// testCA 测试用 CA,签发服务端/客户端证书
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue 签发证书(SAN 为 127.0.0.1),返回证书和私钥 PEM
+func (ca *testCA) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	return ca.issueFor(t, cn, usage, nil, []net.IP{net.ParseIP("127.0.0.1")})
+}
+
+// issueFor 签发指定 SAN 的证书
+func (ca *testCA) issueFor(t *testing.T, cn string, usage x509.ExtKeyUsage, dnsNames []string, ips []net.IP) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     dnsNames,
+		IPAddresses:  ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// newMTLSServer 要求客户端证书的服务端,响应客户端证书的 CN
+func newMTLSServer(t *testing.T, ca *testCA) *httptest.Server {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func writeFile(t *testing.T, path string, data []byte, mod time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	srv := newMTLSServer(t, ca)
+	dir := t.TempDir()
+	caFile, certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	mod := time.Now().Add(-time.Minute)
+	writeFile(t, caFile, ca.pem, mod)
+	certPEM, keyPEM := ca.issue(t, "client-v1", x509.ExtKeyUsageClientAuth)
+	writeFile(t, certFile, certPEM, mod)
+	writeFile(t, keyFile, keyPEM, mod)
+
+	c := New(
+		WithRootCAs(caFile),
+		WithClientCertificate(certFile, keyFile),
+		WithTLSReloadInterval(time.Millisecond),
+		WithMinTLSVersion(tls.VersionTLS13),
+	)
+	get := func() string {
+		t.Helper()
+		resp, err := c.Get(context.Background(), srv.URL)
+		if err != nil {
+			t.Fatalf("请求失败: %v", err)
+		}
+		return resp.String()
+	}
+	if got := get(); got != "client-v1" {
+		t.Errorf("CN = %q, want client-v1", got)
+	}
+
+	// 证书轮换:写入新证书后新连接使用新证书
+	certPEM, keyPEM = ca.issue(t, "client-v2", x509.ExtKeyUsageClientAuth)
+	writeFile(t, certFile, certPEM, mod.Add(time.Second))
+	writeFile(t, keyFile, keyPEM, mod.Add(time.Second))
+	time.Sleep(5 * time.Millisecond)
+	c.CloseIdleConnections()
+	if got := get(); got != "client-v2" {
+		t.Errorf("证书轮换后 CN = %q, want client-v2", got)
+	}
+
+	// 写坏的证书不影响已加载的证书
+	writeFile(t, certFile, []byte("broken"), mod.Add(2*time.Second))
+	time.Sleep(5 * time.Millisecond)
+	c.CloseIdleConnections()
+	if got := get(); got != "client-v2" {
+		t.Errorf("证书文件损坏后 CN = %q, want client-v2", got)
+	}
+}
+
+func TestRootCAsRejectsUnknownServer(t *testing.T) {
+	// httptest 默认证书不是由该 CA 签发,应校验失败
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer srv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeFile(t, caFile, newTestCA(t).pem, time.Now())
+
+	if _, err := New(WithRootCAs(caFile)).Get(context.Background(), srv.URL); err == nil {
+		t.Error("未知 CA 签发的服务端证书应校验失败")
+	}
+}
+
+func TestRootCAsVerifiesHostname(t *testing.T) {
+	ca := newTestCA(t)
+	// 证书由配置的 CA 签发,但只对 evil.example 有效
+	certPEM, keyPEM := ca.issueFor(t, "evil", x509.ExtKeyUsageServerAuth, []string{"evil.example"}, nil)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	defer srv.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeFile(t, caFile, ca.pem, time.Now())
+
+	if _, err := New(WithRootCAs(caFile)).Get(context.Background(), srv.URL); err == nil {
+		t.Fatal("证书主机名与访问的 IP 不匹配时应校验失败")
+	}
+}
+
+func TestRootCAsReload(t *testing.T) {
+	ca1, ca2 := newTestCA(t), newTestCA(t)
+	srv := newMTLSServer(t, ca2)
+	dir := t.TempDir()
+	caFile, certFile, keyFile := filepath.Join(dir, "ca.pem"), filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	mod := time.Now().Add(-time.Minute)
+	writeFile(t, caFile, ca1.pem, mod)
+	certPEM, keyPEM := ca2.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	writeFile(t, certFile, certPEM, mod)
+	writeFile(t, keyFile, keyPEM, mod)
+
+	c := New(WithRootCAs(caFile), WithClientCertificate(certFile, keyFile), WithTLSReloadInterval(time.Millisecond))
+	if _, err := c.Get(context.Background(), srv.URL); err == nil {
+		t.Fatal("服务端证书不是由当前 CA 签发,应校验失败")
+	}
+
+	// CA 轮换后新连接使用新 CA
+	writeFile(t, caFile, ca2.pem, mod.Add(time.Second))
+	time.Sleep(5 * time.Millisecond)
+	if _, err := c.Get(context.Background(), srv.URL); err != nil {
+		t.Fatalf("CA 更新后请求失败: %v", err)
+	}
+}
+
+func TestTLSFilesMissing(t *testing.T) {
+	c := New(WithRootCAs(filepath.Join(t.TempDir(), "missing.pem")))
+	if _, err := c.Get(context.Background(), "https://127.0.0.1:1/"); err == nil {
+		t.Error("CA 文件不存在时请求应失败")
+	}
+	if err := checkTLSFiles("", "client.pem", ""); err == nil {
+		t.Error("证书缺少私钥应返回错误")
+	}
+}
@@ -9,8 +9,10 @@ import (
 	"github.com/aichy126/igo/cache"
 	"github.com/aichy126/igo/config"
 	"github.com/aichy126/igo/db"
+	"github.com/aichy126/igo/httpclient"
 	"github.com/aichy126/igo/lifecycle"
 	"github.com/aichy126/igo/log"
+	"github.com/aichy126/igo/metrics"
 	"github.com/aichy126/igo/web"
 	"github.com/gin-gonic/gin"
 )
@@ -20,6 +22,8 @@ type Application struct {
 	Web   *web.Web
 	DB    *db.DB
 	Cache *cache.Cache
+	// HTTPClients [httpclient.xxx] 配置的客户端,通过 HTTP(name) 获取
+	HTTPClients *httpclient.Manager
 	// 生命周期管理器
 	lifecycle *lifecycle.LifecycleManager
 }
@@ -27,7 +31,7 @@ type Application struct {
 var App *Application
 
 // NewApp 创建应用实例
-// 初始化顺序:config → log → db → cache → web
+// 初始化顺序:config → log → db → cache → httpclient → web
 // 配置了的组件初始化失败会返回错误(fail-fast);db/redis 未配置时跳过,不报错
 func NewApp(ConfigPath string) (*Application, error) {
 	a := new(Application)
@@ -79,6 +83,18 @@ func NewApp(ConfigPath string) (*Application, error) {
 	}
 	a.Cache = cacheInstance
 
+	//httpclient(配置错误返回错误;配置热重载时重建有变化的客户端,失败保留旧客户端)
+	clients, err := httpclient.NewManager(conf)
+	if err != nil {
+		return nil, fmt.Errorf("httpclient 初始化失败: %w", err)
+	}
+	a.HTTPClients = clients
+	a.Conf.AddChangeCallback(func() {
+		if err := a.HTTPClients.Reload(); err != nil {
+			log.Error("httpclient 配置热更新失败,继续使用旧配置", log.Any("error", err))
+		}
+	})
+
 	//web
 	webInstance, err := web.NewWeb(conf)
 	if err != nil {
@@ -90,13 +106,16 @@ func NewApp(ConfigPath string) (*Application, error) {
 	a.lifecycle = lifecycle.NewLifecycleManager()
 
 	// 自动注册所有组件的优雅关闭（按依赖关系反向顺序执行:后注册的先关闭）
-	// 关闭顺序:Web(停止接收新请求) → Cache → DB
+	// 关闭顺序:Web(停止接收新请求) → httpclient → Cache → DB;异步日志由 lifecycle 在最后刷盘
 	a.lifecycle.AddShutdownHook(func() error {
 		return a.DB.Close()
 	})
 	a.lifecycle.AddShutdownHook(func() error {
 		return a.Cache.Close()
 	})
+	a.lifecycle.AddShutdownHook(func() error {
+		return a.HTTPClients.Close()
+	})
 	a.lifecycle.AddShutdownHook(func() error {
 		ctx, cancel := context.WithTimeout(context.Background(), lifecycle.DefaultShutdownTimeout)
 		defer cancel()
@@ -182,7 +201,7 @@ func (a *Application) ReloadConfig() error {
 }
 
 // EnableHealthCheck 注册 GET /health 健康检查路由(可选,一行开启)
-// 返回应用状态以及所有已配置 db/redis 的连通性;任一组件异常时返回 503
+// 返回应用状态以及所有已配置 db/redis 的连通性、redis 连接池统计;任一组件异常时返回 503
 func (a *Application) EnableHealthCheck() *Application {
 	a.Web.Router.GET("/health", func(c *gin.Context) {
 		healthy := true
@@ -220,10 +239,35 @@ func (a *Application) EnableHealthCheck() *Application {
 			status = http.StatusServiceUnavailable
 			statusText = "unhealthy"
 		}
-		c.JSON(status, gin.H{
+		resp := gin.H{
 			"status":     statusText,
 			"components": components,
-		})
+		}
+		if a.Cache != nil && a.Cache.RedisManager != nil {
+			resp["redis_pools"] = a.Cache.PoolStats()
+		}
+		c.JSON(status, resp)
 	})
 	return a
 }
+
+// EnableMetrics 注册 GET /metrics 路由,以 Prometheus 文本格式输出 metrics.Default 中的指标
+// (redis 命令耗时/错误数等框架内置指标,以及业务自行注册的指标)
+func (a *Application) EnableMetrics() *Application {
+	a.Web.Router.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+	return a
+}
+
+// HTTP 获取 [httpclient.name] 配置的客户端,每次调用都取最新配置,不要长期持有返回值
+//
+//	app.HTTP("payment").GetJSON(ctx, "/v1/orders", &out)
+//
+// 未配置时记录错误日志,返回的客户端所有请求都会失败并返回该错误
+func (a *Application) HTTP(name string) *httpclient.Client {
+	c, err := a.HTTPClients.Get(name)
+	if err != nil {
+		log.Error("获取 httpclient 失败", log.String("name", name), log.Any("error", err))
+		return httpclient.ErrorClient(err)
+	}
+	return c
+}
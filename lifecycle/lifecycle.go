@@ -125,6 +125,11 @@ func (lm *LifecycleManager) shutdown() error {
 			err = fmt.Errorf("优雅关闭超时(%s),强制退出", timeout)
 			log.Error("优雅关闭超时", log.Any("timeout", timeout.String()))
 		}
+
+		// 无论钩子是否超时,最后写完异步日志队列,保证退出前的日志不丢失
+		if cerr := log.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("日志刷盘失败: %w", cerr)
+		}
 	})
 	return err
 }
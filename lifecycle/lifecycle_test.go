@@ -2,8 +2,13 @@ package lifecycle
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/aichy126/igo/log"
 )
 
 // TestRunReturnsOnError 验证组件运行错误(如端口占用)会让 Run 立即返回,而不是挂起等信号
@@ -71,6 +76,36 @@ func TestShutdownTimeout(t *testing.T) {
 	}
 }
 
+// TestShutdownTimeoutFlushesAsyncLog 验证关闭钩子超时时仍会写完异步日志队列
+func TestShutdownTimeoutFlushesAsyncLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.log")
+	err := log.InitLogger(path, "info", 10, 1, 1, false,
+		log.WithAsync(log.AsyncConfig{Enabled: true, FlushInterval: time.Hour}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { log.Close() })
+
+	lm := NewLifecycleManager()
+	lm.AddShutdownHook(func() error {
+		log.Info("关闭钩子开始")
+		time.Sleep(time.Second)
+		return nil
+	})
+	if err := lm.GracefulShutdown(100 * time.Millisecond); err == nil {
+		t.Fatal("超时应返回错误")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"关闭钩子开始", "优雅关闭超时"} {
+		if !strings.Contains(string(data), msg) {
+			t.Errorf("关闭后日志文件缺少 %q: %s", msg, data)
+		}
+	}
+}
+
 // TestShutdownOnlyOnce 验证关闭流程只执行一次
 func TestShutdownOnlyOnce(t *testing.T) {
 	lm := NewLifecycleManager()
@@ -0,0 +1,201 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aichy126/igo/metrics"
+	"go.uber.org/zap/zapcore"
+)
+
+// 异步写入默认值
+const (
+	DefaultAsyncQueueSize     = 8192
+	DefaultAsyncFlushInterval = time.Second
+	DefaultAsyncBufferSize    = 256 * 1024
+)
+
+var logAsyncDropped = metrics.NewCounterVec("igo_log_async_dropped_total",
+	"异步日志队列满时被丢弃的日志条数", "output")
+
+// AsyncConfig 异步写入配置,对应 [local.logger.async]
+type AsyncConfig struct {
+	Enabled       bool          // 是否开启
+	QueueSize     int           // 队列长度(条),默认 8192
+	FlushInterval time.Duration // 定时刷盘间隔,默认 1 秒
+	BufferSize    int           // 写缓冲大小(字节),写满即刷盘,默认 256KB
+	Block         bool          // 队列满时阻塞等待(不丢日志);默认丢弃并计数
+}
+
+// WithAsync 日志异步写入:写日志只入队,由后台 goroutine 批量写文件/控制台(syslog 输出不受影响)。
+// 进程退出前需调用 Close(igo 应用的生命周期管理在关闭时自动调用),否则可能丢失队列中的日志
+func WithAsync(c AsyncConfig) LoggerOption {
+	return func(o *loggerOptions) { o.async = c }
+}
+
+// asyncItem 队列元素:日志内容,或 Sync 请求(done 非 nil)
+type asyncItem struct {
+	data []byte
+	done chan error
+}
+
+// asyncWriter 带有界队列的异步 WriteSyncer
+type asyncWriter struct {
+	name    string
+	ws      zapcore.WriteSyncer
+	block   bool
+	queue   chan asyncItem
+	dropped atomic.Uint64
+
+	mu     sync.RWMutex // 保护 closed 与 queue 的关闭
+	closed bool
+	exited chan struct{}
+	err    error // 退出时最后一次写入的错误
+}
+
+func newAsyncWriter(name string, ws zapcore.WriteSyncer, c AsyncConfig) *asyncWriter {
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultAsyncQueueSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultAsyncFlushInterval
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = DefaultAsyncBufferSize
+	}
+	w := &asyncWriter{
+		name:   name,
+		ws:     ws,
+		block:  c.Block,
+		queue:  make(chan asyncItem, c.QueueSize),
+		exited: make(chan struct{}),
+	}
+	go w.run(c.FlushInterval, c.BufferSize)
+	return w
+}
+
+// Write 入队;关闭后直接同步写入
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return w.ws.Write(p)
+	}
+	// zap 会复用 p 的内存,入队前复制
+	item := asyncItem{data: append([]byte(nil), p...)}
+	if w.block {
+		w.queue <- item
+		return len(p), nil
+	}
+	select {
+	case w.queue <- item:
+	default:
+		w.dropped.Add(1)
+		logAsyncDropped.WithLabelValues(w.name).Inc()
+	}
+	return len(p), nil
+}
+
+// Sync 等待队列中已有的日志写入并刷盘(Fatal 等级别 zap 会自动调用)
+func (w *asyncWriter) Sync() error {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return w.ws.Sync()
+	}
+	done := make(chan error, 1)
+	w.queue <- asyncItem{done: done}
+	w.mu.RUnlock()
+	return <-done
+}
+
+// Close 写完队列中的日志,之后的写入改为同步
+func (w *asyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+	<-w.exited
+	return w.err
+}
+
+func (w *asyncWriter) run(interval time.Duration, size int) {
+	defer close(w.exited)
+	buf := bufio.NewWriterSize(w.ws, size) // 写满时自动写入底层
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case item, ok := <-w.queue:
+			if !ok {
+				w.err = buf.Flush()
+				return
+			}
+			if item.done != nil {
+				item.done <- errors.Join(buf.Flush(), w.ws.Sync())
+				continue
+			}
+			buf.Write(item.data)
+		case <-ticker.C:
+			buf.Flush()
+		}
+	}
+}
+
+// asyncWriters 当前 Logger 使用的异步 writer,由 Close 关闭
+var (
+	asyncMu      sync.Mutex
+	asyncWriters []*asyncWriter
+)
+
+// wrapAsync 开启异步时包装 ws 并登记,否则原样返回
+func (o loggerOptions) wrapAsync(name string, ws zapcore.WriteSyncer) zapcore.WriteSyncer {
+	if !o.async.Enabled {
+		return ws
+	}
+	w := newAsyncWriter(name, ws, o.async)
+	asyncMu.Lock()
+	asyncWriters = append(asyncWriters, w)
+	asyncMu.Unlock()
+	return w
+}
+
+// closeAsync 关闭所有异步 writer,返回第一个错误
+func closeAsync() error {
+	asyncMu.Lock()
+	writers := asyncWriters
+	asyncWriters = nil
+	asyncMu.Unlock()
+	var firstErr error
+	for _, w := range writers {
+		if err := w.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// AsyncDropped 当前异步 writer 因队列满丢弃的日志条数之和
+func AsyncDropped() uint64 {
+	asyncMu.Lock()
+	defer asyncMu.Unlock()
+	var n uint64
+	for _, w := range asyncWriters {
+		n += w.dropped.Load()
+	}
+	return n
+}
+
+// Close 用于进程退出前:停止采样丢弃汇总(输出剩余的统计),写完异步队列中的日志,之后的日志同步写入
+func Close() error {
+	// 先输出汇总,汇总日志也要经过异步队列写入
+	stopSummary()
+	return closeAsync()
+}
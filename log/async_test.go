@@ -0,0 +1,93 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// slowWriter 每次写入前等待,模拟慢磁盘
+type slowWriter struct {
+	mu    sync.Mutex
+	delay time.Duration
+	data  strings.Builder
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.data.Write(p)
+}
+
+func (w *slowWriter) Sync() error { return nil }
+
+func (w *slowWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.data.String()
+}
+
+func TestAsyncFlushOnClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.log")
+	err := InitLogger(path, "info", 10, 1, 1, false,
+		WithAsync(AsyncConfig{Enabled: true, Block: true, FlushInterval: time.Hour}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 1000 {
+		Info("async", Int("i", i))
+	}
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries := readEntries(t, path)
+	if n := countMsg(entries, "async"); n != 1000 {
+		t.Fatalf("Close 后应写入全部 1000 条,实际 %d", n)
+	}
+
+	// Close 之后的日志同步写入
+	Info("after close")
+	if n := countMsg(readEntries(t, path), "after close"); n != 1 {
+		t.Fatalf("Close 后的日志应同步写入,实际 %d 条", n)
+	}
+}
+
+func TestAsyncSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.log")
+	err := InitLogger(path, "info", 10, 1, 1, false,
+		WithAsync(AsyncConfig{Enabled: true, FlushInterval: time.Hour}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { Close() })
+	Info("before sync")
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "before sync") {
+		t.Fatal("刷盘间隔内不应写入文件")
+	}
+	if n := countMsg(readEntries(t, path), "before sync"); n != 1 { // readEntries 会调用 Sync
+		t.Fatalf("Sync 后应写入文件,实际 %d 条", n)
+	}
+}
+
+func TestAsyncDropWhenFull(t *testing.T) {
+	sw := &slowWriter{delay: 5 * time.Millisecond}
+	w := newAsyncWriter("test", zapcore.AddSync(sw), AsyncConfig{QueueSize: 4, BufferSize: 1})
+	for i := range 100 {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	written := strings.Count(sw.String(), "\n")
+	dropped := int(w.dropped.Load())
+	if dropped == 0 || written+dropped != 100 {
+		t.Fatalf("队列满时应丢弃并计数: written=%d dropped=%d", written, dropped)
+	}
+}
@@ -26,6 +26,13 @@ type loggerConf struct {
 	MaxAge     int // 文件最多保存多少天
 	Debug      bool
 	Access     bool
+
+	SamplingInitial    int // 采样:每条消息每秒前 initial 条全部输出,0 不采样
+	SamplingThereafter int // 采样:之后每 thereafter 条输出 1 条
+	RateLimit          int // 每条消息每秒最多输出条数,0 不限制
+
+	Outputs []OutputConfig // [[local.logger.outputs]],为空时只写 Dir/Name(debug 时同时输出到控制台)
+	Async   AsyncConfig    // [local.logger.async]
 }
 
 // readLoggerConf 从配置中读取日志配置并填充默认值
@@ -39,6 +46,18 @@ func readLoggerConf(conf *config.Config) loggerConf {
 		MaxAge:     conf.GetInt("local.logger.max_age"),
 		Debug:      conf.GetBool("local.debug"),
 		Access:     conf.GetBool("local.logger.access"),
+
+		SamplingInitial:    conf.GetInt("local.logger.sampling.initial"),
+		SamplingThereafter: conf.GetInt("local.logger.sampling.thereafter"),
+		RateLimit:          conf.GetInt("local.logger.rate_limit"),
+
+		Async: AsyncConfig{
+			Enabled:       conf.GetBool("local.logger.async.enabled"),
+			QueueSize:     conf.GetInt("local.logger.async.queue_size"),
+			FlushInterval: time.Duration(conf.GetInt("local.logger.async.flush_interval")) * time.Millisecond,
+			BufferSize:    conf.GetInt("local.logger.async.buffer_size"),
+			Block:         conf.GetBool("local.logger.async.block"),
+		},
 	}
 	if lc.Dir == "" {
 		lc.Dir = "./logs"
@@ -62,8 +81,14 @@ func readLoggerConf(conf *config.Config) loggerConf {
 func NewLog(conf *config.Config) (*Log, error) {
 	log := new(Log)
 	lc := readLoggerConf(conf)
+	if err := conf.UnmarshalKey("local.logger.outputs", &lc.Outputs); err != nil {
+		return log, fmt.Errorf("解析 local.logger.outputs 失败: %w", err)
+	}
+	resolveOutputFiles(lc.Outputs, lc.Dir)
 	filename := fmt.Sprintf("%s/%s", lc.Dir, lc.Name)
-	err := InitLogger(filename, lc.Level, lc.MaxSize, lc.MaxBackups, lc.MaxAge, lc.Debug)
+	err := InitLogger(filename, lc.Level, lc.MaxSize, lc.MaxBackups, lc.MaxAge, lc.Debug,
+		WithSampling(lc.SamplingInitial, lc.SamplingThereafter), WithRateLimit(lc.RateLimit),
+		WithOutputs(lc.Outputs...), WithAsync(lc.Async))
 	return log, err
 }
 
@@ -149,36 +174,59 @@ func GetLevel() Level {
 	return atomicLevel.Level()
 }
 
-// InitLogger 初始化全局 Logger
-func InitLogger(filename, level string, maxSize, maxBackups, maxAge int, debug bool) (err error) {
-	writeSyncer := getLogWriter(filename, maxSize, maxBackups, maxAge)
-	encoder := getEncoder()
+// InitLogger 初始化全局 Logger;opts 可开启采样、限流(WithSampling/WithRateLimit),
+// 配置多个输出(WithOutputs)或异步写入(WithAsync)
+func InitLogger(filename, level string, maxSize, maxBackups, maxAge int, debug bool, opts ...LoggerOption) (err error) {
 	var l = new(zapcore.Level)
 	err = l.UnmarshalText([]byte(level))
 	if err != nil {
 		return fmt.Errorf("无效的日志级别 %q: %w", level, err)
 	}
-	atomicLevel.SetLevel(*l)
+	var o loggerOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	// 先写完上一个 Logger 异步队列中的日志
+	if err := closeAsync(); err != nil {
+		fmt.Printf("异步日志刷盘失败: %v\n", err)
+	}
+
 	var baseCore zapcore.Core
-	if debug {
+	switch {
+	case len(o.outputs) > 0:
+		baseCore, err = newTeeCore(o, filename, maxSize, maxBackups, maxAge)
+		if err != nil {
+			closeAsync()
+			return err
+		}
+	case debug:
 		//输出到日志和控制台
-		baseCore = zapcore.NewCore(encoder, zapcore.NewMultiWriteSyncer(writeSyncer, zapcore.AddSync(os.Stdout)), atomicLevel)
-	} else {
+		writeSyncer := o.wrapAsync(filename, getLogWriter(filename, maxSize, maxBackups, maxAge))
+		baseCore = zapcore.NewCore(getEncoder(), zapcore.NewMultiWriteSyncer(writeSyncer, o.wrapAsync(TargetStdout, zapcore.AddSync(os.Stdout))), atomicLevel)
+	default:
 		//只输出到日志
-		baseCore = zapcore.NewCore(encoder, writeSyncer, atomicLevel)
+		baseCore = zapcore.NewCore(getEncoder(), o.wrapAsync(filename, getLogWriter(filename, maxSize, maxBackups, maxAge)), atomicLevel)
 	}
+	atomicLevel.SetLevel(*l)
 
 	// 包装为hookCore，支持日志钩子
 	hookCoreInstance = newHookCore(baseCore)
 
-	lg = zap.New(hookCoreInstance, zap.AddCaller(), zap.AddCallerSkip(1))
+	// 采样/限流在最外层:被丢弃的日志既不写入也不触发钩子
+	core := wrapSampling(hookCoreInstance, o)
+
+	lg = zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
 	zap.ReplaceGlobals(lg) // 替换zap包中全局的logger实例，后续在其他包中只需使用zap.L()调用即可
 	setStd(newLogger(lg, LevelToNum(level)))
 	return
 }
 
 func getEncoder() zapcore.Encoder {
+	return zapcore.NewJSONEncoder(getEncoderConfig())
+}
 
+func getEncoderConfig() zapcore.EncoderConfig {
 	customTimeEncoder := func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
 		enc.AppendString(t.Format("2006-01-02 15:04:05.000"))
 	}
@@ -190,7 +238,7 @@ func getEncoder() zapcore.Encoder {
 	encoderConfig.EncodeDuration = zapcore.SecondsDurationEncoder
 	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
 	encoderConfig.EncodeTime = customTimeEncoder
-	return zapcore.NewJSONEncoder(encoderConfig)
+	return encoderConfig
 }
 
 func getLogWriter(filename string, maxSize, maxBackup, maxAge int) zapcore.WriteSyncer {
@@ -0,0 +1,91 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder 输出 logfmt 格式(key=value,按字段顺序,空格分隔)。
+// 基于 JSON 编码器:先编码为 JSON 再逐个字段转换,嵌套对象/数组以紧凑 JSON 作为值
+type logfmtEncoder struct {
+	zapcore.Encoder
+}
+
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{Encoder: zapcore.NewJSONEncoder(cfg)}
+}
+
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	return &logfmtEncoder{Encoder: e.Encoder.Clone()}
+}
+
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	js, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer js.Free()
+
+	out := logfmtPool.Get()
+	dec := json.NewDecoder(bytes.NewReader(js.Bytes()))
+	if _, err := dec.Token(); err != nil { // {
+		out.Free()
+		return nil, err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			out.Free()
+			return nil, err
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			out.Free()
+			return nil, err
+		}
+		if out.Len() > 0 {
+			out.AppendByte(' ')
+		}
+		key, _ := tok.(string)
+		out.AppendString(logfmtKey(key))
+		out.AppendByte('=')
+		appendLogfmtValue(out, raw)
+	}
+	out.AppendByte('\n')
+	return out, nil
+}
+
+// logfmtKey 去掉 key 中的空白、= 和引号
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+func appendLogfmtValue(out *buffer.Buffer, raw json.RawMessage) {
+	s := string(raw)
+	if len(raw) > 0 && raw[0] == '"' {
+		if unquoted, err := strconv.Unquote(s); err == nil {
+			s = unquoted
+		} else {
+			_ = json.Unmarshal(raw, &s) // JSON 转义(如 \u0000)strconv 不一定能解析
+		}
+	}
+	if s == "" || strings.ContainsFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '=' || r == '"' || !unicode.IsPrint(r)
+	}) {
+		s = strconv.Quote(s)
+	}
+	out.AppendString(s)
+}
@@ -0,0 +1,160 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 输出目标
+const (
+	TargetFile   = "file"
+	TargetStdout = "stdout"
+	TargetStderr = "stderr"
+	TargetSyslog = "syslog"
+)
+
+// 输出编码
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+	EncodingLogfmt  = "logfmt"
+)
+
+// OutputConfig 一个日志输出,对应配置 [[local.logger.outputs]]
+type OutputConfig struct {
+	Target   string `mapstructure:"target"`   // file(默认)/stdout/stderr/syslog
+	Encoding string `mapstructure:"encoding"` // json(默认)/console/logfmt
+	Level    string `mapstructure:"level"`    // 该输出的最低级别,与全局级别同时生效;空表示只按全局级别
+
+	// target = file
+	File       string `mapstructure:"file"`        // 文件路径,为空时使用主日志文件;相对路径位于 local.logger.dir 下
+	MaxSize    int    `mapstructure:"max_size"`    // 单位 MB,0 使用 local.logger.max_size
+	MaxBackups int    `mapstructure:"max_backups"` // 0 使用 local.logger.max_backups
+	MaxAge     int    `mapstructure:"max_age"`     // 单位天,0 使用 local.logger.max_age
+
+	// target = syslog
+	Network  string `mapstructure:"network"`  // unixgram/unix/udp/tcp,为空时按 address 判断
+	Address  string `mapstructure:"address"`  // 为空时使用本机 /dev/log
+	Tag      string `mapstructure:"tag"`      // 为空时使用进程名
+	Facility string `mapstructure:"facility"` // user(默认)/daemon/local0~local7
+}
+
+// WithOutputs 设置日志输出;设置后替代 InitLogger 的默认输出(filename 及 debug 时的控制台输出)
+func WithOutputs(outputs ...OutputConfig) LoggerOption {
+	return func(o *loggerOptions) { o.outputs = outputs }
+}
+
+// newOutputCore 创建一个输出的 core;filename 及轮转参数为 file 输出未配置时使用的默认值
+func newOutputCore(o loggerOptions, out OutputConfig, filename string, maxSize, maxBackups, maxAge int) (zapcore.Core, error) {
+	enc, err := newEncoder(out.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	enab := zapcore.LevelEnabler(atomicLevel)
+	if out.Level != "" {
+		var min zapcore.Level
+		if err := min.UnmarshalText([]byte(out.Level)); err != nil {
+			return nil, fmt.Errorf("日志输出 %s 的级别 %q 无效: %w", out.Target, out.Level, err)
+		}
+		enab = zap.LevelEnablerFunc(func(l zapcore.Level) bool { return l >= min && atomicLevel.Enabled(l) })
+	}
+
+	var core zapcore.Core
+	switch out.Target {
+	case "", TargetFile:
+		file := out.File
+		if file == "" {
+			file = filename
+		}
+		ws := getLogWriter(file, orDefault(out.MaxSize, maxSize), orDefault(out.MaxBackups, maxBackups), orDefault(out.MaxAge, maxAge))
+		core = zapcore.NewCore(enc, o.wrapAsync(file, ws), enab)
+	case TargetStdout:
+		core = zapcore.NewCore(enc, o.wrapAsync(TargetStdout, zapcore.Lock(os.Stdout)), enab)
+	case TargetStderr:
+		core = zapcore.NewCore(enc, o.wrapAsync(TargetStderr, zapcore.Lock(os.Stderr)), enab)
+	case TargetSyslog:
+		w, err := newSyslogWriter(out.Network, out.Address, out.Tag, out.Facility)
+		if err != nil {
+			return nil, err
+		}
+		core = &syslogCore{LevelEnabler: enab, enc: enc, w: w}
+	default:
+		return nil, fmt.Errorf("未知的日志输出目标 %q(可选 file/stdout/stderr/syslog)", out.Target)
+	}
+	return &levelCore{Core: core, enab: enab}, nil
+}
+
+// newTeeCore 按 outputs 创建合并的 core
+func newTeeCore(o loggerOptions, filename string, maxSize, maxBackups, maxAge int) (zapcore.Core, error) {
+	cores := make([]zapcore.Core, 0, len(o.outputs))
+	for _, out := range o.outputs {
+		core, err := newOutputCore(o, out, filename, maxSize, maxBackups, maxAge)
+		if err != nil {
+			return nil, err
+		}
+		cores = append(cores, core)
+	}
+	return zapcore.NewTee(cores...), nil
+}
+
+func newEncoder(encoding string) (zapcore.Encoder, error) {
+	switch strings.ToLower(encoding) {
+	case "", EncodingJSON:
+		return getEncoder(), nil
+	case EncodingConsole:
+		return zapcore.NewConsoleEncoder(getEncoderConfig()), nil
+	case EncodingLogfmt:
+		return newLogfmtEncoder(getEncoderConfig()), nil
+	}
+	return nil, fmt.Errorf("未知的日志编码 %q(可选 json/console/logfmt)", encoding)
+}
+
+// resolveOutputFiles 把相对路径的输出文件放到 dir 下
+func resolveOutputFiles(outputs []OutputConfig, dir string) {
+	for i := range outputs {
+		if f := outputs[i].File; f != "" && !filepath.IsAbs(f) {
+			outputs[i].File = filepath.Join(dir, f)
+		}
+	}
+}
+
+func orDefault(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+// levelCore 在 Write 时也按级别过滤:hookCore 直接调用内层 Write,
+// 多个输出合并(Tee)后需要每个输出自己丢弃低于其级别的日志
+type levelCore struct {
+	zapcore.Core
+	enab zapcore.LevelEnabler
+}
+
+func (c *levelCore) Enabled(l zapcore.Level) bool {
+	return c.enab.Enabled(l)
+}
+
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enab: c.enab}
+}
+
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *levelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
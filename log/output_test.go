@@ -0,0 +1,71 @@
+package log
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMultipleOutputs(t *testing.T) {
+	dir := t.TempDir()
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+
+	err = InitLogger(filepath.Join(dir, "log.log"), "info", 10, 1, 1, false, WithOutputs(
+		OutputConfig{},
+		OutputConfig{File: filepath.Join(dir, "error.log"), Encoding: EncodingLogfmt, Level: "error"},
+		OutputConfig{Target: TargetSyslog, Address: udp.LocalAddr().String(), Tag: "igo-test", Facility: "local0", Level: "warn"},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	Info("普通日志", String("user", "tom"))
+	Error("出错了", String("reason", "db timeout"), Int("code", 7))
+
+	entries := readEntries(t, filepath.Join(dir, "log.log"))
+	if countMsg(entries, "普通日志") != 1 || countMsg(entries, "出错了") != 1 {
+		t.Fatalf("主日志应包含全部日志: %v", entries)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "error.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("error.log 应只有 1 行: %q", data)
+	}
+	for _, want := range []string{"level=ERROR", "msg=出错了", `reason="db timeout"`, "code=7"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("logfmt 输出缺少 %s: %s", want, lines[0])
+		}
+	}
+
+	// Info 低于 syslog 输出的 warn 级别,只收到 Error
+	buf := make([]byte, 4096)
+	udp.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := udp.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<131>") || !strings.Contains(msg, "igo-test[") || !strings.Contains(msg, `"msg":"出错了"`) {
+		t.Fatalf("syslog 消息不正确: %s", msg)
+	}
+}
+
+func TestInvalidOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.log")
+	if err := InitLogger(path, "info", 10, 1, 1, false, WithOutputs(OutputConfig{Target: "kafka"})); err == nil {
+		t.Fatal("未知的输出目标应返回错误")
+	}
+	if err := InitLogger(path, "info", 10, 1, 1, false, WithOutputs(OutputConfig{Encoding: "xml"})); err == nil {
+		t.Fatal("未知的编码应返回错误")
+	}
+}
@@ -0,0 +1,165 @@
+package log
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aichy126/igo/metrics"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultDropSummaryInterval 丢弃统计日志的默认输出间隔
+const DefaultDropSummaryInterval = time.Minute
+
+// 丢弃统计中最多按消息区分的条数,超过的合并计入 total
+const maxDropMessages = 1000
+
+var logDropped = metrics.NewCounterVec("igo_log_dropped_total",
+	"因采样或限流被丢弃的日志条数", "level")
+
+// LoggerOption InitLogger 的可选配置
+type LoggerOption func(*loggerOptions)
+
+type loggerOptions struct {
+	initial, thereafter int           // 采样:每条消息每秒前 initial 条全部输出,之后每 thereafter 条输出 1 条
+	rateLimit           int           // 每条消息每秒最多输出条数
+	summaryInterval     time.Duration // 丢弃统计输出间隔
+	outputs             []OutputConfig
+	async               AsyncConfig
+}
+
+// WithSampling 按消息采样:同一级别、同一消息每秒前 initial 条全部输出,之后每 thereafter 条输出 1 条
+// (thereafter <= 0 时之后全部丢弃)。initial <= 0 不采样
+func WithSampling(initial, thereafter int) LoggerOption {
+	return func(o *loggerOptions) { o.initial, o.thereafter = initial, thereafter }
+}
+
+// WithRateLimit 同一级别、同一消息每秒最多输出 perSecond 条(在采样之后生效),<= 0 不限制
+func WithRateLimit(perSecond int) LoggerOption {
+	return func(o *loggerOptions) { o.rateLimit = perSecond }
+}
+
+// WithDropSummary 设置丢弃统计日志的输出间隔(默认 1 分钟);该间隔内有日志被丢弃时输出一条 Warn 汇总
+func WithDropSummary(interval time.Duration) LoggerOption {
+	return func(o *loggerOptions) { o.summaryInterval = interval }
+}
+
+// dropStats 统计被丢弃的日志
+type dropStats struct {
+	mu    sync.Mutex
+	total uint64
+	byMsg map[string]uint64
+}
+
+func (d *dropStats) hook(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+	if dec&zapcore.LogDropped == 0 {
+		return
+	}
+	logDropped.WithLabelValues(ent.Level.String()).Inc()
+	d.mu.Lock()
+	d.total++
+	if _, ok := d.byMsg[ent.Message]; ok || len(d.byMsg) < maxDropMessages {
+		d.byMsg[ent.Message]++
+	}
+	d.mu.Unlock()
+}
+
+// take 取出并清空统计
+func (d *dropStats) take() (uint64, map[string]uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	total, byMsg := d.total, d.byMsg
+	d.total, d.byMsg = 0, make(map[string]uint64)
+	return total, byMsg
+}
+
+// summarize 输出一条丢弃汇总,按丢弃数取前 10 条消息
+func (d *dropStats) summarize(l *zap.Logger, interval time.Duration) {
+	total, byMsg := d.take()
+	if total == 0 {
+		return
+	}
+	msgs := make([]string, 0, len(byMsg))
+	for msg := range byMsg {
+		msgs = append(msgs, msg)
+	}
+	sort.Slice(msgs, func(i, j int) bool { return byMsg[msgs[i]] > byMsg[msgs[j]] })
+	top := make(map[string]uint64, 10)
+	for _, msg := range msgs[:min(len(msgs), 10)] {
+		top[msg] = byMsg[msg]
+	}
+	l.Warn("日志采样/限流丢弃统计",
+		zap.Uint64("dropped", total),
+		zap.Duration("interval", interval),
+		zap.Any("top_messages", top),
+	)
+}
+
+// summaryStop 停止上一次 InitLogger 启动的汇总 goroutine(输出剩余的统计后返回)
+var (
+	summaryMu   sync.Mutex
+	summaryStop func()
+)
+
+// stopSummary 停止汇总 goroutine 并输出剩余的丢弃统计,未启动时无操作
+func stopSummary() {
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+	stopSummaryLocked()
+}
+
+func stopSummaryLocked() {
+	if summaryStop != nil {
+		summaryStop()
+		summaryStop = nil
+	}
+}
+
+// wrapSampling 按配置在 core 外包装采样与限流,返回包装后的 core;
+// 有丢弃时定期通过未经采样的 core 输出汇总
+func wrapSampling(core zapcore.Core, o loggerOptions) zapcore.Core {
+	summaryMu.Lock()
+	defer summaryMu.Unlock()
+	stopSummaryLocked()
+	if o.initial <= 0 && o.rateLimit <= 0 {
+		return core
+	}
+
+	stats := &dropStats{byMsg: make(map[string]uint64)}
+	sampled := core
+	// 限流在内层:作用于采样后的输出
+	if o.rateLimit > 0 {
+		sampled = zapcore.NewSamplerWithOptions(sampled, time.Second, o.rateLimit, 0, zapcore.SamplerHook(stats.hook))
+	}
+	if o.initial > 0 {
+		sampled = zapcore.NewSamplerWithOptions(sampled, time.Second, o.initial, max(o.thereafter, 0), zapcore.SamplerHook(stats.hook))
+	}
+
+	interval := o.summaryInterval
+	if interval <= 0 {
+		interval = DefaultDropSummaryInterval
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	summaryStop = func() {
+		close(stop)
+		<-done
+	}
+	summary := zap.New(core)
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				stats.summarize(summary, interval)
+			case <-stop:
+				stats.summarize(summary, interval)
+				return
+			}
+		}
+	}()
+	return sampled
+}
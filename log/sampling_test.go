@@ -0,0 +1,112 @@
+package log
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readEntries 读取 JSON 日志文件中的所有条目
+func readEntries(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	if err := Sync(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []map[string]any
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("日志不是 JSON: %s", sc.Text())
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func countMsg(entries []map[string]any, msg string) int {
+	n := 0
+	for _, e := range entries {
+		if e["msg"] == msg {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSamplingAndRateLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.log")
+	err := InitLogger(path, "info", 10, 1, 1, false,
+		WithSampling(10, 100), WithRateLimit(5), WithDropSummary(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(stopSummary)
+
+	for range 1000 {
+		Error("hot loop")
+	}
+	Info("other message")
+	entries := readEntries(t, path)
+	// 采样后 1000 条中保留前 10 条 + 每 100 条 1 条,再被限流为每秒 5 条
+	if n := countMsg(entries, "hot loop"); n != 5 {
+		t.Errorf("hot loop 输出 %d 条, want 5", n)
+	}
+	if n := countMsg(entries, "other message"); n != 1 {
+		t.Errorf("其它消息不受影响,输出 %d 条", n)
+	}
+
+	// 重新初始化时输出上一次的丢弃汇总
+	if err := InitLogger(path, "info", 10, 1, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	var summary map[string]any
+	for _, e := range readEntries(t, path) {
+		if e["msg"] == "日志采样/限流丢弃统计" {
+			summary = e
+		}
+	}
+	if summary == nil {
+		t.Fatal("缺少丢弃汇总日志")
+	}
+	if summary["dropped"] != float64(995) {
+		t.Errorf("dropped = %v, want 995", summary["dropped"])
+	}
+	if top, _ := summary["top_messages"].(map[string]any); top["hot loop"] != float64(995) {
+		t.Errorf("top_messages = %v", summary["top_messages"])
+	}
+}
+
+// TestCloseWritesDropSummary Close 时停止汇总 goroutine,剩余的丢弃统计在异步队列关闭前写入
+func TestCloseWritesDropSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.log")
+	err := InitLogger(path, "info", 10, 1, 1, false,
+		WithSampling(1, 0), WithDropSummary(time.Hour), WithAsync(AsyncConfig{Enabled: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 10 {
+		Warn("noisy")
+	}
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+	summaryMu.Lock()
+	running := summaryStop != nil
+	summaryMu.Unlock()
+	if running {
+		t.Error("Close 后汇总 goroutine 应已停止")
+	}
+	entries := readEntries(t, path)
+	if countMsg(entries, "日志采样/限流丢弃统计") != 1 {
+		t.Fatalf("Close 后应输出丢弃汇总: %v", entries)
+	}
+}
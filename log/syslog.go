@@ -0,0 +1,150 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultSyslogAddress 本机 syslog 的 unix socket
+const DefaultSyslogAddress = "/dev/log"
+
+var syslogFacilities = map[string]int{
+	"user": 1, "daemon": 3,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSeverity zap 级别对应的 syslog severity
+func syslogSeverity(l zapcore.Level) int {
+	switch {
+	case l >= zapcore.DPanicLevel:
+		return 2 // crit
+	case l >= zapcore.ErrorLevel:
+		return 3 // err
+	case l >= zapcore.WarnLevel:
+		return 4 // warning
+	case l >= zapcore.InfoLevel:
+		return 6 // info
+	}
+	return 7 // debug
+}
+
+// syslogWriter 按 RFC 3164 格式发送到 syslog,连接断开时下次写入重连
+type syslogWriter struct {
+	network  string
+	address  string
+	tag      string
+	facility int
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogWriter(network, address, tag, facility string) (*syslogWriter, error) {
+	if address == "" {
+		address = DefaultSyslogAddress
+	}
+	if network == "" {
+		network = "udp"
+		if strings.HasPrefix(address, "/") {
+			network = "unixgram"
+		}
+	}
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+	if facility == "" {
+		facility = "user"
+	}
+	f, ok := syslogFacilities[strings.ToLower(facility)]
+	if !ok {
+		return nil, fmt.Errorf("未知的 syslog facility %q", facility)
+	}
+	hostname, _ := os.Hostname()
+	return &syslogWriter{network: network, address: address, tag: tag, facility: f, hostname: hostname}, nil
+}
+
+func (w *syslogWriter) local() bool {
+	return strings.HasPrefix(w.network, "unix")
+}
+
+// write 发送一条日志;写入失败时重连重试一次
+func (w *syslogWriter) write(l zapcore.Level, t time.Time, msg []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	for range 2 {
+		if w.conn == nil {
+			if w.conn, err = net.Dial(w.network, w.address); err != nil {
+				w.conn = nil
+				return fmt.Errorf("连接 syslog %s %s 失败: %w", w.network, w.address, err)
+			}
+		}
+		if _, err = w.conn.Write(w.format(l, t, msg)); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return fmt.Errorf("写入 syslog 失败: %w", err)
+}
+
+// format 本机 socket 省略 hostname(由 syslog 守护进程补充),网络发送带上 hostname
+func (w *syslogWriter) format(l zapcore.Level, t time.Time, msg []byte) []byte {
+	pri := w.facility*8 + syslogSeverity(l)
+	var b bytes.Buffer
+	if w.local() {
+		fmt.Fprintf(&b, "<%d>%s %s[%d]: ", pri, t.Format(time.Stamp), w.tag, os.Getpid())
+	} else {
+		fmt.Fprintf(&b, "<%d>%s %s %s[%d]: ", pri, t.Format(time.RFC3339), w.hostname, w.tag, os.Getpid())
+	}
+	b.Write(bytes.TrimRight(msg, "\n"))
+	if w.network == "tcp" {
+		b.WriteByte('\n') // 流式连接以换行分隔
+	}
+	return b.Bytes()
+}
+
+// syslogCore 按条目级别设置 syslog severity 的 core
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   *syslogWriter
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, w: c.w}
+}
+
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	return c.w.write(ent.Level, ent.Time, buf.Bytes())
+}
+
+func (c *syslogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,157 @@
+// Package metrics 轻量指标库:计数器、仪表盘、直方图(支持标签),
+// 以 Prometheus 文本格式输出,不引入 prometheus client 依赖。
+//
+// 框架内置组件(redis、httpclient 等)的指标注册在 Default 中,
+// 调用 igo.App.EnableMetrics() 后可通过 GET /metrics 抓取。
+//
+//	var orders = metrics.NewCounterVec("app_orders_total", "订单数", "status")
+//	orders.WithLabelValues("paid").Inc()
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefBuckets 默认直方图分桶(秒),适合 HTTP 请求等毫秒到秒级的耗时
+var DefBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector 一个指标族
+type collector interface {
+	name() string
+	kind() string
+	help() string
+	labelNames() []string
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表,并发安全
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]collector
+}
+
+// Default 默认注册表
+var Default = NewRegistry()
+
+// NewRegistry 创建注册表
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// getOrRegister 同名指标已存在且类型、标签一致时返回已有的,否则 panic(指标定义冲突属于编码错误)
+func getOrRegister[T collector](r *Registry, c T, labels []string) T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if old, ok := r.collectors[c.name()]; ok {
+		exist, same := old.(T)
+		if !same || !slices.Equal(old.labelNames(), labels) {
+			panic(fmt.Sprintf("指标 %s 已注册为不同的类型或标签", c.name()))
+		}
+		return exist
+	}
+	r.collectors[c.name()] = c
+	return c
+}
+
+// WriteText 以 Prometheus 文本格式输出所有指标,按名称排序
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	cs := make([]collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		cs = append(cs, c)
+	}
+	r.mu.RUnlock()
+	slices.SortFunc(cs, func(a, b collector) int { return strings.Compare(a.name(), b.name()) })
+
+	bw := bufio.NewWriter(w)
+	for _, c := range cs {
+		fmt.Fprintf(bw, "# HELP %s %s\n", c.name(), escapeHelp(c.help()))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", c.name(), c.kind())
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler 返回输出指标的 http.Handler
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	})
+}
+
+// NewCounterVec 在 Default 中创建(或获取已有的)计数器
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.CounterVec(name, help, labels...)
+}
+
+// NewGaugeVec 在 Default 中创建(或获取已有的)仪表盘
+func NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return Default.GaugeVec(name, help, labels...)
+}
+
+// NewHistogramVec 在 Default 中创建(或获取已有的)直方图,buckets 为 nil 时使用 DefBuckets
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.HistogramVec(name, help, buckets, labels...)
+}
+
+// 输出格式辅助
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+// writeSample 输出一行样本;extra 为附加标签(如直方图的 le)
+func writeSample(w *bufio.Writer, name string, labels, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l)
+			w.WriteString(`="`)
+			w.WriteString(labelEscaper.Replace(values[i]))
+			w.WriteByte('"')
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName)
+			w.WriteString(`="`)
+			w.WriteString(extraValue)
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
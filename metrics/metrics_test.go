@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.CounterVec("req_total", "请求数", "method", "code")
+	c.WithLabelValues("GET", "200").Inc()
+	c.WithLabelValues("GET", "200").Add(2)
+	c.WithLabelValues("POST", `5"0`).Inc()
+	g := r.GaugeVec("inflight", "进行中")
+	g.WithLabelValues().Set(3)
+	g.WithLabelValues().Dec()
+	h := r.HistogramVec("latency_seconds", "耗时", []float64{0.5, 0.1}, "path")
+	for _, v := range []float64{0.05, 0.1, 0.3, 2} {
+		h.WithLabelValues("/a").Observe(v)
+	}
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP inflight 进行中
+# TYPE inflight gauge
+inflight 2
+# HELP latency_seconds 耗时
+# TYPE latency_seconds histogram
+latency_seconds_bucket{path="/a",le="0.1"} 2
+latency_seconds_bucket{path="/a",le="0.5"} 3
+latency_seconds_bucket{path="/a",le="+Inf"} 4
+latency_seconds_sum{path="/a"} 2.45
+latency_seconds_count{path="/a"} 4
+# HELP req_total 请求数
+# TYPE req_total counter
+req_total{method="GET",code="200"} 3
+req_total{method="POST",code="5\"0"} 1
+`
+	if b.String() != want {
+		t.Errorf("WriteText =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") || w.Body.String() != want {
+		t.Errorf("Handler 输出不一致: %q", w.Body.String())
+	}
+}
+
+func TestRegisterConflict(t *testing.T) {
+	r := NewRegistry()
+	a := r.CounterVec("x_total", "x", "a")
+	if b := r.CounterVec("x_total", "x", "a"); a != b {
+		t.Error("同名同标签应返回已有指标")
+	}
+	for name, fn := range map[string]func(){
+		"类型不同": func() { r.GaugeVec("x_total", "x", "a") },
+		"标签不同": func() { r.CounterVec("x_total", "x", "b") },
+		"标签个数": func() { a.WithLabelValues("1", "2") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s 应 panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,242 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// vec 按标签值区分的一组指标
+type vec[T any] struct {
+	fname    string
+	fhelp    string
+	labels   []string
+	newChild func() *T
+
+	mu       sync.RWMutex
+	children map[string]*child[T]
+}
+
+type child[T any] struct {
+	values []string
+	m      *T
+}
+
+func newVec[T any](name, help string, labels []string, newChild func() *T) vec[T] {
+	return vec[T]{
+		fname:    name,
+		fhelp:    help,
+		labels:   labels,
+		newChild: newChild,
+		children: make(map[string]*child[T]),
+	}
+}
+
+func (v *vec[T]) name() string { return v.fname }
+func (v *vec[T]) help() string { return v.fhelp }
+
+func (v *vec[T]) labelNames() []string { return v.labels }
+
+func (v *vec[T]) with(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("指标 %s 需要 %d 个标签值,实际 %d 个", v.fname, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mu.RLock()
+	c, ok := v.children[key]
+	v.mu.RUnlock()
+	if ok {
+		return c.m
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if c, ok = v.children[key]; !ok {
+		c = &child[T]{values: slices.Clone(values), m: v.newChild()}
+		v.children[key] = c
+	}
+	return c.m
+}
+
+// sorted 按标签值排序,输出稳定
+func (v *vec[T]) sorted() []*child[T] {
+	v.mu.RLock()
+	cs := make([]*child[T], 0, len(v.children))
+	for _, c := range v.children {
+		cs = append(cs, c)
+	}
+	v.mu.RUnlock()
+	slices.SortFunc(cs, func(a, b *child[T]) int { return slices.Compare(a.values, b.values) })
+	return cs
+}
+
+// atomicFloat 并发安全的 float64
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) add(delta float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// Counter 只增不减的计数器
+type Counter struct {
+	v atomicFloat
+}
+
+// Inc 加 1
+func (c *Counter) Inc() { c.v.add(1) }
+
+// Add 增加 delta,delta 必须非负
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("计数器不能减少")
+	}
+	c.v.add(delta)
+}
+
+// Value 当前值
+func (c *Counter) Value() float64 { return c.v.load() }
+
+// CounterVec 带标签的计数器
+type CounterVec struct {
+	vec[Counter]
+}
+
+// CounterVec 创建(或获取已有的)计数器
+func (r *Registry) CounterVec(name, help string, labels ...string) *CounterVec {
+	return getOrRegister(r, &CounterVec{newVec(name, help, labels, func() *Counter { return new(Counter) })}, labels)
+}
+
+// WithLabelValues 按标签值(顺序与定义一致)获取计数器
+func (v *CounterVec) WithLabelValues(values ...string) *Counter {
+	return v.with(values)
+}
+
+func (v *CounterVec) kind() string { return "counter" }
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	for _, c := range v.sorted() {
+		writeSample(w, v.fname, v.labels, c.values, "", "", c.m.Value())
+	}
+}
+
+// Gauge 可增可减的仪表盘
+type Gauge struct {
+	v atomicFloat
+}
+
+// Set 设置为 val
+func (g *Gauge) Set(val float64) { g.v.bits.Store(math.Float64bits(val)) }
+
+// Add 增加 delta(可为负)
+func (g *Gauge) Add(delta float64) { g.v.add(delta) }
+
+// Inc 加 1
+func (g *Gauge) Inc() { g.v.add(1) }
+
+// Dec 减 1
+func (g *Gauge) Dec() { g.v.add(-1) }
+
+// Value 当前值
+func (g *Gauge) Value() float64 { return g.v.load() }
+
+// GaugeVec 带标签的仪表盘
+type GaugeVec struct {
+	vec[Gauge]
+}
+
+// GaugeVec 创建(或获取已有的)仪表盘
+func (r *Registry) GaugeVec(name, help string, labels ...string) *GaugeVec {
+	return getOrRegister(r, &GaugeVec{newVec(name, help, labels, func() *Gauge { return new(Gauge) })}, labels)
+}
+
+// WithLabelValues 按标签值获取仪表盘
+func (v *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return v.with(values)
+}
+
+func (v *GaugeVec) kind() string { return "gauge" }
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	for _, c := range v.sorted() {
+		writeSample(w, v.fname, v.labels, c.values, "", "", c.m.Value())
+	}
+}
+
+// Histogram 直方图
+type Histogram struct {
+	upper  []float64       // 各分桶上界,升序
+	counts []atomic.Uint64 // 落在各分桶的次数(非累计),最后一个为 +Inf
+	sum    atomicFloat
+	count  atomic.Uint64
+}
+
+// Observe 记录一个观测值
+func (h *Histogram) Observe(v float64) {
+	h.counts[sort.SearchFloat64s(h.upper, v)].Add(1)
+	h.sum.add(v)
+	h.count.Add(1)
+}
+
+// Count 观测次数
+func (h *Histogram) Count() uint64 { return h.count.Load() }
+
+// Sum 观测值之和
+func (h *Histogram) Sum() float64 { return h.sum.load() }
+
+// HistogramVec 带标签的直方图
+type HistogramVec struct {
+	vec[Histogram]
+	buckets []float64
+}
+
+// HistogramVec 创建(或获取已有的)直方图,buckets 为 nil 时使用 DefBuckets
+func (r *Registry) HistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	h := &HistogramVec{buckets: buckets}
+	h.vec = newVec(name, help, labels, func() *Histogram {
+		return &Histogram{upper: buckets, counts: make([]atomic.Uint64, len(buckets)+1)}
+	})
+	return getOrRegister(r, h, labels)
+}
+
+// WithLabelValues 按标签值获取直方图
+func (v *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return v.with(values)
+}
+
+func (v *HistogramVec) kind() string { return "histogram" }
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	for _, c := range v.sorted() {
+		h := c.m
+		var cum uint64
+		for i, upper := range h.upper {
+			cum += h.counts[i].Load()
+			writeSample(w, v.fname+"_bucket", v.labels, c.values, "le", formatFloat(upper), float64(cum))
+		}
+		cum += h.counts[len(h.upper)].Load()
+		writeSample(w, v.fname+"_bucket", v.labels, c.values, "le", "+Inf", float64(cum))
+		writeSample(w, v.fname+"_sum", v.labels, c.values, "", "", h.Sum())
+		writeSample(w, v.fname+"_count", v.labels, c.values, "", "", float64(h.Count()))
+	}
+}
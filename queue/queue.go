@@ -0,0 +1,293 @@
+// Package queue 提供基于 Redis Stream 的后台任务队列:
+// 消费组保证同一任务只被一个 worker 处理,处理成功才 ACK;
+// worker 崩溃遗留的未确认任务会被其他 worker 重新认领,失败任务按退避重试,
+// 超过重试次数进入死信队列;延迟任务先放在有序集合中,到期后移入 Stream。
+//
+// 基本用法:
+//
+//	rds, _ := igo.App.Cache.Get("igorediskey")
+//	q := queue.New(rds, "email")
+//	_, err := q.Enqueue(ctx, "welcome", WelcomeEmail{UserID: 1})
+//
+//	w := queue.NewWorker(q, queue.WithConcurrency(5))
+//	w.Handle("welcome", func(ctx context.Context, job *queue.Job) error {
+//		var msg WelcomeEmail
+//		if err := job.Bind(&msg); err != nil {
+//			return err
+//		}
+//		return sendEmail(ctx, msg)
+//	})
+//	w.Register(igo.App) //随应用启动,关闭时等待进行中的任务处理完
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	icontext "github.com/aichy126/igo/context"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultKeyPrefix Redis 中队列 key 的默认前缀
+const DefaultKeyPrefix = "igo:queue:"
+
+const (
+	// jobField Stream 消息中保存任务 JSON 的字段名
+	jobField = "job"
+	// consumerGroup 消费组名;一个队列只有一个消费组,任务确认后即从 Stream 删除
+	consumerGroup = "igo-workers"
+)
+
+// Job 一个任务
+type Job struct {
+	ID        string          `json:"id"`                   // 任务 ID,重试时保持不变
+	Type      string          `json:"type"`                 // 任务类型,Worker 按类型分发给 handler
+	Payload   json.RawMessage `json:"payload,omitempty"`    // 任务参数(JSON)
+	Attempts  int             `json:"attempts"`             // 已失败次数,首次执行为 0
+	TraceID   string          `json:"trace_id,omitempty"`   // 入队时 ctx 中的 traceId,处理时透传
+	CreatedAt int64           `json:"created_at"`           // 首次入队时间,毫秒时间戳
+	LastError string          `json:"last_error,omitempty"` // 最近一次失败原因
+
+	streamID string // 当前这次投递的 Stream 消息 ID
+}
+
+// Bind 将 Payload 解析到 v
+func (j *Job) Bind(v any) error {
+	if len(j.Payload) == 0 {
+		return errors.New("任务 payload 为空")
+	}
+	return json.Unmarshal(j.Payload, v)
+}
+
+// String 实现 fmt.Stringer,用于日志
+func (j *Job) String() string {
+	return j.Type + "#" + j.ID + " attempts=" + strconv.Itoa(j.Attempts)
+}
+
+func (j *Job) encode() (string, error) {
+	b, err := json.Marshal(j)
+	return string(b), err
+}
+
+func decodeJob(msg redis.XMessage) (*Job, error) {
+	raw, _ := msg.Values[jobField].(string)
+	job := new(Job)
+	if err := json.Unmarshal([]byte(raw), job); err != nil {
+		return nil, fmt.Errorf("任务 %s 解析失败: %w", msg.ID, err)
+	}
+	job.streamID = msg.ID
+	return job, nil
+}
+
+// promoteScript 把到期的延迟任务移入 Stream
+// KEYS[1] 延迟任务有序集合、KEYS[2] Stream;ARGV: 当前时间(毫秒)、单次最多移动数
+var promoteScript = redis.NewScript(`
+local jobs = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+for _, job in ipairs(jobs) do
+	redis.call('XADD', KEYS[2], '*', 'job', job)
+	redis.call('ZREM', KEYS[1], job)
+end
+return #jobs
+`)
+
+// Queue 一个命名的任务队列,可同时用于生产和消费,并发安全
+type Queue struct {
+	redis   *cache.Redis
+	name    string
+	stream  string // 就绪任务
+	delayed string // 延迟任务(有序集合,score 为到期毫秒时间戳)
+	dead    string // 死信
+}
+
+// Option 队列配置项
+type Option func(*Queue)
+
+// WithKeyPrefix 设置 Redis key 前缀(默认 "igo:queue:")
+func WithKeyPrefix(prefix string) Option {
+	return func(q *Queue) { q.setKeys(prefix) }
+}
+
+// New 创建任务队列
+func New(r *cache.Redis, name string, opts ...Option) *Queue {
+	q := &Queue{redis: r, name: name}
+	q.setKeys(DefaultKeyPrefix)
+	for _, opt := range opts {
+		opt(q)
+	}
+	return q
+}
+
+// setKeys 队列名用 {} 包裹作为 hash tag,集群模式下同一队列的 key 落在同一个 slot,
+// 移动延迟任务的 Lua 脚本才能同时操作有序集合和 Stream
+func (q *Queue) setKeys(prefix string) {
+	base := prefix + "{" + q.name + "}"
+	q.stream = base
+	q.delayed = base + ":delayed"
+	q.dead = base + ":dead"
+}
+
+// Name 队列名
+func (q *Queue) Name() string {
+	return q.name
+}
+
+// Enqueue 立即入队,payload 会被序列化为 JSON;返回任务 ID
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload any) (string, error) {
+	job, err := newJob(ctx, jobType, payload)
+	if err != nil {
+		return "", err
+	}
+	if err := q.add(ctx, q.redis, job); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// EnqueueIn 延迟 delay 后执行
+func (q *Queue) EnqueueIn(ctx context.Context, delay time.Duration, jobType string, payload any) (string, error) {
+	return q.EnqueueAt(ctx, time.Now().Add(delay), jobType, payload)
+}
+
+// EnqueueAt 在指定时间执行(精度取决于 Worker 的延迟任务轮询间隔)
+func (q *Queue) EnqueueAt(ctx context.Context, at time.Time, jobType string, payload any) (string, error) {
+	job, err := newJob(ctx, jobType, payload)
+	if err != nil {
+		return "", err
+	}
+	if err := q.schedule(ctx, q.redis, job, at); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+func newJob(ctx context.Context, jobType string, payload any) (*Job, error) {
+	if jobType == "" {
+		return nil, errors.New("任务类型不能为空")
+	}
+	job := &Job{
+		ID:        uuid.New().String(),
+		Type:      jobType,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("任务 payload 序列化失败: %w", err)
+		}
+		job.Payload = b
+	}
+	if ictx, ok := ctx.(icontext.IContext); ok {
+		job.TraceID = ictx.GetString("traceId")
+	}
+	return job, nil
+}
+
+func (q *Queue) add(ctx context.Context, c redis.Cmdable, job *Job) error {
+	data, err := job.encode()
+	if err != nil {
+		return err
+	}
+	return c.XAdd(ctx, &redis.XAddArgs{Stream: q.stream, Values: []string{jobField, data}}).Err()
+}
+
+func (q *Queue) schedule(ctx context.Context, c redis.Cmdable, job *Job, at time.Time) error {
+	data, err := job.encode()
+	if err != nil {
+		return err
+	}
+	return c.ZAdd(ctx, q.delayed, redis.Z{Score: float64(at.UnixMilli()), Member: data}).Err()
+}
+
+// promote 把到期的延迟任务移入 Stream,返回移动的数量
+func (q *Queue) promote(ctx context.Context, batch int) (int, error) {
+	return promoteScript.Run(ctx, q.redis, []string{q.delayed, q.stream}, time.Now().UnixMilli(), batch).Int()
+}
+
+// Len 就绪任务数(含已投递未确认的)
+func (q *Queue) Len(ctx context.Context) (int64, error) {
+	return q.redis.XLen(ctx, q.stream).Result()
+}
+
+// DelayedLen 延迟任务数
+func (q *Queue) DelayedLen(ctx context.Context) (int64, error) {
+	return q.redis.ZCard(ctx, q.delayed).Result()
+}
+
+// DeadLetters 读取最早进入死信队列的 count 个任务,LastError 为最后一次失败原因
+func (q *Queue) DeadLetters(ctx context.Context, count int64) ([]*Job, error) {
+	msgs, err := q.redis.XRangeN(ctx, q.dead, "-", "+", count).Result()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, 0, len(msgs))
+	for _, msg := range msgs {
+		job, err := decodeJob(msg)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// RetryDeadLetter 将死信任务重新入队(重置失败次数),用于修复问题后手动重放
+func (q *Queue) RetryDeadLetter(ctx context.Context, job *Job) error {
+	if job.streamID == "" {
+		return errors.New("只能重放通过 DeadLetters 读取的任务")
+	}
+	retry := *job
+	retry.Attempts = 0
+	retry.LastError = ""
+	_, err := q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if err := q.add(ctx, pipe, &retry); err != nil {
+			return err
+		}
+		pipe.XDel(ctx, q.dead, job.streamID)
+		return nil
+	})
+	return err
+}
+
+// bury 将任务移入死信队列并确认原消息
+func (q *Queue) bury(ctx context.Context, job *Job) error {
+	data, err := job.encode()
+	if err != nil {
+		return err
+	}
+	_, err = q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAdd(ctx, &redis.XAddArgs{Stream: q.dead, Values: []string{jobField, data}})
+		pipe.XAck(ctx, q.stream, consumerGroup, job.streamID)
+		pipe.XDel(ctx, q.stream, job.streamID)
+		return nil
+	})
+	return err
+}
+
+// retry 按退避时间重新排入延迟队列并确认原消息
+func (q *Queue) retry(ctx context.Context, job *Job, delay time.Duration) error {
+	_, err := q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if err := q.schedule(ctx, pipe, job, time.Now().Add(delay)); err != nil {
+			return err
+		}
+		pipe.XAck(ctx, q.stream, consumerGroup, job.streamID)
+		pipe.XDel(ctx, q.stream, job.streamID)
+		return nil
+	})
+	return err
+}
+
+// ack 确认任务处理完成并从 Stream 删除
+func (q *Queue) ack(ctx context.Context, job *Job) error {
+	_, err := q.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.XAck(ctx, q.stream, consumerGroup, job.streamID)
+		pipe.XDel(ctx, q.stream, job.streamID)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,332 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	icontext "github.com/aichy126/igo/context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestQueue(t *testing.T, name string) (*Queue, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	options := &redis.UniversalOptions{Addrs: []string{mr.Addr()}}
+	r := cache.NewRedis(redis.NewClient(options.Simple()), options)
+	t.Cleanup(func() { _ = r.Close() })
+	return New(r, name), mr
+}
+
+// newTestWorker 缩短各种间隔,重试不等待
+func newTestWorker(t *testing.T, q *Queue, opts ...WorkerOption) *Worker {
+	t.Helper()
+	opts = append([]WorkerOption{
+		WithPollInterval(10 * time.Millisecond),
+		WithBackoff(func(int) time.Duration { return 0 }),
+	}, opts...)
+	w := NewWorker(q, opts...)
+	w.opts.block = 20 * time.Millisecond
+	return w
+}
+
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal(msg)
+}
+
+type payload struct {
+	N int `json:"n"`
+}
+
+func TestEnqueueAndProcess(t *testing.T) {
+	q, _ := newTestQueue(t, "basic")
+	ctx := icontext.NewContext()
+	ctx.SetMeta("traceId", "trace-1")
+	for i := range 3 {
+		if _, err := q.Enqueue(ctx, "add", payload{N: i + 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var sum atomic.Int64
+	var traceID atomic.Value
+	w := newTestWorker(t, q)
+	w.Handle("add", func(ctx context.Context, job *Job) error {
+		var p payload
+		if err := job.Bind(&p); err != nil {
+			return err
+		}
+		sum.Add(int64(p.N))
+		traceID.Store(ctx.(icontext.IContext).GetString("traceId"))
+		return nil
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	waitFor(t, func() bool { return sum.Load() == 6 }, "任务未全部处理")
+	if got := traceID.Load(); got != "trace-1" {
+		t.Errorf("traceId = %v, want trace-1", got)
+	}
+	waitFor(t, func() bool { n, _ := q.Len(t.Context()); return n == 0 }, "处理完的任务应从 Stream 删除")
+}
+
+func TestRetryThenDeadLetter(t *testing.T) {
+	q, _ := newTestQueue(t, "retry")
+	var calls atomic.Int32
+	w := newTestWorker(t, q, WithMaxRetries(2))
+	w.Handle("flaky", func(ctx context.Context, job *Job) error {
+		if calls.Add(1) == 1 {
+			return errors.New("first")
+		}
+		return nil
+	})
+	w.Handle("broken", func(ctx context.Context, job *Job) error {
+		panic("boom")
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	if _, err := q.Enqueue(t.Context(), "flaky", nil); err != nil {
+		t.Fatal(err)
+	}
+	id, err := q.Enqueue(t.Context(), "broken", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue(t.Context(), "unknown", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var dead []*Job
+	waitFor(t, func() bool {
+		dead, _ = q.DeadLetters(t.Context(), 10)
+		return len(dead) == 2
+	}, "broken 和 unknown 任务应进入死信队列")
+	if calls.Load() != 2 {
+		t.Errorf("flaky 执行 %d 次, want 2", calls.Load())
+	}
+
+	var broken *Job
+	for _, job := range dead {
+		switch job.Type {
+		case "broken":
+			broken = job
+			if job.ID != id || job.Attempts != 3 || job.LastError == "" {
+				t.Errorf("broken 死信 = %+v, want 失败 3 次且记录错误", job)
+			}
+		case "unknown":
+			if job.Attempts != 1 {
+				t.Errorf("没有 handler 的任务不应重试, attempts = %d", job.Attempts)
+			}
+		}
+	}
+	if broken == nil {
+		t.Fatal("死信中缺少 broken 任务")
+	}
+
+	// 重放死信
+	if err := q.RetryDeadLetter(t.Context(), broken); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		dead, _ = q.DeadLetters(t.Context(), 10)
+		return len(dead) == 2 && dead[1].Type == "broken"
+	}, "重放的任务再次失败后应重新进入死信队列")
+}
+
+func TestDelayedJob(t *testing.T) {
+	q, _ := newTestQueue(t, "delayed")
+	var doneAt atomic.Int64
+	w := newTestWorker(t, q)
+	w.Handle("later", func(ctx context.Context, job *Job) error {
+		doneAt.Store(time.Now().UnixMilli())
+		return nil
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	start := time.Now()
+	if _, err := q.EnqueueIn(t.Context(), 200*time.Millisecond, "later", nil); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := q.DelayedLen(t.Context()); n != 1 {
+		t.Fatalf("DelayedLen = %d, want 1", n)
+	}
+	waitFor(t, func() bool { return doneAt.Load() != 0 }, "延迟任务未执行")
+	if elapsed := doneAt.Load() - start.UnixMilli(); elapsed < 200 {
+		t.Errorf("延迟任务提前执行, 入队后 %dms 即执行", elapsed)
+	}
+}
+
+// TestReclaim 模拟 worker 拉取任务后崩溃:其他 worker 认领并按失败重试
+func TestReclaim(t *testing.T) {
+	q, _ := newTestQueue(t, "reclaim")
+	if _, err := q.Enqueue(t.Context(), "job", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	crashed := newTestWorker(t, q)
+	if err := crashed.createGroup(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := q.redis.XReadGroup(t.Context(), &redis.XReadGroupArgs{
+		Group: consumerGroup, Consumer: "crashed", Streams: []string{q.stream, ">"}, Count: 1,
+	}).Result()
+	if err != nil || len(msgs[0].Messages) != 1 {
+		t.Fatalf("模拟崩溃的 worker 拉取任务失败: %v", err)
+	}
+
+	var got atomic.Pointer[Job]
+	w := newTestWorker(t, q, WithClaimIdle(50*time.Millisecond))
+	w.Handle("job", func(ctx context.Context, job *Job) error {
+		got.Store(job)
+		return nil
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	waitFor(t, func() bool { return got.Load() != nil }, "未确认的任务应被认领并重新执行")
+	if job := got.Load(); job.Attempts != 1 || job.LastError == "" {
+		t.Errorf("认领的任务应计一次失败, got %+v", job)
+	}
+}
+
+func TestStopDrainsInflight(t *testing.T) {
+	q, _ := newTestQueue(t, "drain")
+	started := make(chan struct{})
+	var finished atomic.Bool
+	w := newTestWorker(t, q)
+	w.Handle("slow", func(ctx context.Context, job *Job) error {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		finished.Store(true)
+		return nil
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue(t.Context(), "slow", nil); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := w.Stop(); err != nil {
+		t.Fatalf("Stop error: %v", err)
+	}
+	if !finished.Load() {
+		t.Error("Stop 应等待进行中的任务完成")
+	}
+	pending, err := q.redis.XPending(context.Background(), q.stream, consumerGroup).Result()
+	if err != nil || pending.Count != 0 {
+		t.Errorf("任务应已确认, pending=%+v err=%v", pending, err)
+	}
+}
+
+func TestSlowHandlerAcked(t *testing.T) {
+	// handler 耗时超过确认超时,仍应确认成功
+	old := ackTimeout
+	ackTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { ackTimeout = old })
+
+	q, _ := newTestQueue(t, "slow-ack")
+	var done atomic.Bool
+	w := newTestWorker(t, q)
+	w.Handle("slow", func(ctx context.Context, job *Job) error {
+		time.Sleep(150 * time.Millisecond)
+		done.Store(true)
+		return nil
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if _, err := q.Enqueue(t.Context(), "slow", nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, done.Load, "任务未处理")
+	waitFor(t, func() bool {
+		pending, err := q.redis.XPending(context.Background(), q.stream, consumerGroup).Result()
+		return err == nil && pending.Count == 0
+	}, "耗时较长的任务应被确认")
+}
+
+func TestStopDrainTimeout(t *testing.T) {
+	q, _ := newTestQueue(t, "timeout")
+	started := make(chan struct{})
+	w := newTestWorker(t, q, WithDrainTimeout(50*time.Millisecond))
+	w.Handle("stuck", func(ctx context.Context, job *Job) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue(t.Context(), "stuck", nil); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if err := w.Stop(); err == nil {
+		t.Error("超过 drain timeout 应返回错误")
+	}
+	waitFor(t, func() bool { n, _ := q.DelayedLen(t.Context()); return n == 1 }, "被取消的任务应排入重试")
+}
+
+type fakeLifecycle struct {
+	mu       sync.Mutex
+	startup  []func() error
+	shutdown []func() error
+}
+
+func (f *fakeLifecycle) AddStartupHook(hook func() error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.startup = append(f.startup, hook)
+}
+
+func (f *fakeLifecycle) AddShutdownHook(hook func() error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.shutdown = append(f.shutdown, hook)
+}
+
+func TestRegister(t *testing.T) {
+	q, _ := newTestQueue(t, "lifecycle")
+	w := newTestWorker(t, q)
+	if err := w.Start(); err == nil {
+		t.Error("没有 handler 时 Start 应返回错误")
+	}
+	w.Handle("noop", func(ctx context.Context, job *Job) error { return nil })
+
+	lc := &fakeLifecycle{}
+	w.Register(lc)
+	if len(lc.startup) != 1 || len(lc.shutdown) != 1 {
+		t.Fatal("Register 应注册启动和关闭钩子")
+	}
+	if err := lc.startup[0](); err != nil {
+		t.Fatal(err)
+	}
+	if err := lc.shutdown[0](); err != nil {
+		t.Fatal(err)
+	}
+}
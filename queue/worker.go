@@ -0,0 +1,448 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	icontext "github.com/aichy126/igo/context"
+	"github.com/aichy126/igo/log"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Worker 默认配置
+const (
+	DefaultConcurrency  = 10
+	DefaultMaxRetries   = 3
+	DefaultClaimIdle    = 5 * time.Minute
+	DefaultPollInterval = time.Second
+	// DefaultDrainTimeout 关闭时等待进行中任务的时间,小于 lifecycle.DefaultShutdownTimeout,给后续关闭钩子留出时间
+	DefaultDrainTimeout = 8 * time.Second
+)
+
+// 单次批量操作(认领、移动延迟任务)的最大条数
+const batchSize = 100
+
+// ackTimeout 任务处理完后确认/重试/移入死信的超时,不受 handler ctx 取消影响
+var ackTimeout = 5 * time.Second
+
+// ErrNoHandler 任务类型没有注册 handler,这类任务不重试,直接进入死信队列
+var ErrNoHandler = errors.New("任务类型没有注册 handler")
+
+// HandlerFunc 任务处理函数,返回 error 表示失败,按退避重试;panic 视为失败
+// ctx 携带入队时的 traceId,Worker 关闭等待超时后会被取消
+type HandlerFunc func(ctx context.Context, job *Job) error
+
+// BackoffFunc 第 attempt 次失败(从 1 开始)后,距离下次重试的等待时间
+type BackoffFunc func(attempt int) time.Duration
+
+// DefaultBackoff 指数退避:1s、2s、4s……最长 10 分钟
+func DefaultBackoff(attempt int) time.Duration {
+	if attempt > 10 {
+		return 10 * time.Minute
+	}
+	return min(time.Second<<(attempt-1), 10*time.Minute)
+}
+
+// Lifecycle 应用生命周期,*igo.Application 实现了该接口
+type Lifecycle interface {
+	AddStartupHook(hook func() error)
+	AddShutdownHook(hook func() error)
+}
+
+type workerOptions struct {
+	concurrency  int
+	consumer     string
+	maxRetries   int
+	backoff      BackoffFunc
+	claimIdle    time.Duration
+	block        time.Duration
+	pollInterval time.Duration
+	drainTimeout time.Duration
+}
+
+// WorkerOption Worker 配置项
+type WorkerOption func(*workerOptions)
+
+// WithConcurrency 同时处理的最大任务数,默认 10
+func WithConcurrency(n int) WorkerOption {
+	return func(o *workerOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithConsumerName 消费者名,默认 主机名-随机串;同一消费组内必须唯一
+func WithConsumerName(name string) WorkerOption {
+	return func(o *workerOptions) { o.consumer = name }
+}
+
+// WithMaxRetries 失败后最多重试次数,默认 3;超过后进入死信队列,0 表示不重试
+func WithMaxRetries(n int) WorkerOption {
+	return func(o *workerOptions) {
+		if n >= 0 {
+			o.maxRetries = n
+		}
+	}
+}
+
+// WithBackoff 自定义重试退避,默认 DefaultBackoff
+func WithBackoff(f BackoffFunc) WorkerOption {
+	return func(o *workerOptions) { o.backoff = f }
+}
+
+// WithClaimIdle 投递后超过该时间仍未确认的任务视为 worker 已崩溃,由其他 worker 认领并计一次失败;
+// 默认 5 分钟,必须大于任务的最长处理时间,否则正在处理的任务会被重复执行
+func WithClaimIdle(d time.Duration) WorkerOption {
+	return func(o *workerOptions) {
+		if d > 0 {
+			o.claimIdle = d
+		}
+	}
+}
+
+// WithPollInterval 延迟任务(含重试)的轮询间隔,默认 1 秒,即延迟任务的执行精度
+func WithPollInterval(d time.Duration) WorkerOption {
+	return func(o *workerOptions) {
+		if d > 0 {
+			o.pollInterval = d
+		}
+	}
+}
+
+// WithDrainTimeout 关闭时等待进行中任务的时间,默认 8 秒;超时后取消 handler 的 ctx
+func WithDrainTimeout(d time.Duration) WorkerOption {
+	return func(o *workerOptions) {
+		if d > 0 {
+			o.drainTimeout = d
+		}
+	}
+}
+
+// Worker 消费一个队列的任务,按任务类型分发给 handler
+type Worker struct {
+	queue    *Queue
+	opts     workerOptions
+	handlers map[string]HandlerFunc
+
+	mu        sync.Mutex
+	started   bool
+	stop      context.CancelFunc // 停止拉取
+	jobCtx    context.Context    // handler 的 ctx
+	jobCancel context.CancelFunc
+	loops     sync.WaitGroup
+	jobs      sync.WaitGroup
+	inflight  atomic.Int64
+	slots     chan struct{}
+}
+
+// NewWorker 创建 Worker,通过 Handle 注册 handler 后调用 Start(或 Register 交给应用生命周期管理)
+func NewWorker(q *Queue, opts ...WorkerOption) *Worker {
+	hostname, _ := os.Hostname()
+	o := workerOptions{
+		concurrency:  DefaultConcurrency,
+		consumer:     hostname + "-" + uuid.New().String()[:8],
+		maxRetries:   DefaultMaxRetries,
+		backoff:      DefaultBackoff,
+		claimIdle:    DefaultClaimIdle,
+		block:        time.Second,
+		pollInterval: DefaultPollInterval,
+		drainTimeout: DefaultDrainTimeout,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Worker{
+		queue:    q,
+		opts:     o,
+		handlers: make(map[string]HandlerFunc),
+	}
+}
+
+// Handle 注册任务类型的 handler,必须在 Start 之前调用
+func (w *Worker) Handle(jobType string, h HandlerFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		panic(fmt.Sprintf("队列 [%s] worker 已启动,不能再注册 handler [%s]", w.queue.name, jobType))
+	}
+	w.handlers[jobType] = h
+}
+
+// Register 将 Worker 交给应用生命周期管理:启动钩子中 Start,关闭钩子中 Stop
+//
+//	w.Register(igo.App)
+func (w *Worker) Register(lc Lifecycle) {
+	lc.AddStartupHook(w.Start)
+	lc.AddShutdownHook(w.Stop)
+}
+
+// Start 创建消费组并开始消费,立即返回
+func (w *Worker) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.started {
+		return fmt.Errorf("队列 [%s] worker 已启动", w.queue.name)
+	}
+	if len(w.handlers) == 0 {
+		return fmt.Errorf("队列 [%s] worker 没有注册任何 handler", w.queue.name)
+	}
+	if err := w.createGroup(context.Background()); err != nil {
+		return fmt.Errorf("队列 [%s] 创建消费组失败: %w", w.queue.name, err)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	w.stop = stop
+	w.jobCtx, w.jobCancel = context.WithCancel(context.Background())
+	w.slots = make(chan struct{}, w.opts.concurrency)
+	w.started = true
+
+	w.loops.Add(3)
+	go w.fetchLoop(ctx)
+	go w.tickLoop(ctx, w.opts.pollInterval, w.promote)
+	go w.tickLoop(ctx, min(max(w.opts.claimIdle/2, time.Second), time.Minute), w.reclaim)
+
+	log.Info("队列 worker 已启动", log.String("queue", w.queue.name), log.String("consumer", w.opts.consumer),
+		log.Int("concurrency", w.opts.concurrency))
+	return nil
+}
+
+// Stop 停止拉取新任务并等待进行中的任务处理完;
+// 超过 drain timeout 仍未完成时取消 handler 的 ctx 并返回错误,未确认的任务稍后会被其他 worker 认领
+func (w *Worker) Stop() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.started {
+		return nil
+	}
+	w.started = false
+	w.stop()
+	w.loops.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		w.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		w.jobCancel()
+		log.Info("队列 worker 已停止", log.String("queue", w.queue.name))
+		return nil
+	case <-time.After(w.opts.drainTimeout):
+		w.jobCancel()
+		return fmt.Errorf("队列 [%s] 还有 %d 个任务未在 %s 内处理完", w.queue.name, w.inflight.Load(), w.opts.drainTimeout)
+	}
+}
+
+func (w *Worker) createGroup(ctx context.Context) error {
+	// 从头消费:消费组创建之前入队的任务也会被处理
+	err := w.queue.redis.XGroupCreateMkStream(ctx, w.queue.stream, consumerGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// fetchLoop 有空闲处理槽位时从消费组拉取新任务
+func (w *Worker) fetchLoop(ctx context.Context) {
+	defer w.loops.Done()
+	for {
+		// 至少占到一个槽位再拉取,避免拉到任务却无法处理
+		select {
+		case w.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		n := 1
+	fill:
+		for n < w.opts.concurrency {
+			select {
+			case w.slots <- struct{}{}:
+				n++
+			default:
+				break fill
+			}
+		}
+
+		streams, err := w.queue.redis.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    consumerGroup,
+			Consumer: w.opts.consumer,
+			Streams:  []string{w.queue.stream, ">"},
+			Count:    int64(n),
+			Block:    w.opts.block,
+		}).Result()
+
+		var msgs []redis.XMessage
+		if len(streams) > 0 {
+			msgs = streams[0].Messages
+		}
+		for i := len(msgs); i < n; i++ {
+			<-w.slots
+		}
+		for _, msg := range msgs {
+			w.dispatch(msg, nil)
+		}
+
+		if err != nil && !errors.Is(err, redis.Nil) {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Warn("队列拉取任务失败", log.String("queue", w.queue.name), log.Any("error", err))
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				_ = w.createGroup(ctx)
+			}
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// tickLoop 按间隔执行 f,直到 ctx 取消
+func (w *Worker) tickLoop(ctx context.Context, interval time.Duration, f func(ctx context.Context)) {
+	defer w.loops.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f(ctx)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// promote 将到期的延迟任务移入 Stream
+func (w *Worker) promote(ctx context.Context) {
+	for ctx.Err() == nil {
+		n, err := w.queue.promote(ctx, batchSize)
+		if err != nil {
+			log.Warn("队列移动延迟任务失败", log.String("queue", w.queue.name), log.Any("error", err))
+			return
+		}
+		if n < batchSize {
+			return
+		}
+	}
+}
+
+// reclaim 认领超过 claimIdle 仍未确认的任务(处理它的 worker 大概率已崩溃),按一次失败处理
+func (w *Worker) reclaim(ctx context.Context) {
+	start := "0-0"
+	for ctx.Err() == nil {
+		msgs, next, err := w.queue.redis.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   w.queue.stream,
+			Group:    consumerGroup,
+			Consumer: w.opts.consumer,
+			MinIdle:  w.opts.claimIdle,
+			Start:    start,
+			Count:    batchSize,
+		}).Result()
+		if err != nil {
+			log.Warn("队列认领超时任务失败", log.String("queue", w.queue.name), log.Any("error", err))
+			return
+		}
+		for _, msg := range msgs {
+			select {
+			case w.slots <- struct{}{}:
+			case <-ctx.Done():
+				return // 已认领未处理的任务会在 claimIdle 后再次被认领
+			}
+			w.dispatch(msg, fmt.Errorf("任务超过 %s 未确认,处理它的 worker 可能已崩溃", w.opts.claimIdle))
+		}
+		if next == "0-0" || next == "" {
+			return
+		}
+		start = next
+	}
+}
+
+// dispatch 在新 goroutine 中处理一条消息(调用方已占用一个槽位);
+// reclaimed 非 nil 表示这是被认领的消息,直接按该错误计一次失败,不再执行 handler
+func (w *Worker) dispatch(msg redis.XMessage, reclaimed error) {
+	w.jobs.Add(1)
+	w.inflight.Add(1)
+	go func() {
+		defer func() {
+			w.inflight.Add(-1)
+			<-w.slots
+			w.jobs.Done()
+		}()
+
+		job, err := decodeJob(msg)
+		if err != nil {
+			// 无法解析的消息永远不可能处理成功,记录后直接确认丢弃
+			log.Error("队列任务解析失败,已丢弃", log.String("queue", w.queue.name), log.String("id", msg.ID), log.Any("error", err))
+			ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+			defer cancel()
+			_ = w.queue.ack(ctx, &Job{streamID: msg.ID})
+			return
+		}
+
+		if reclaimed == nil {
+			reclaimed = w.run(job)
+		}
+		// 确认超时从 handler 返回后开始计算,不受 handler 耗时影响
+		ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+		defer cancel()
+		if reclaimed == nil {
+			err = w.queue.ack(ctx, job)
+		} else {
+			err = w.fail(ctx, job, reclaimed)
+		}
+		if err != nil {
+			// 确认失败的任务会在 claimIdle 后被重新认领
+			log.Warn("队列任务确认失败", log.String("queue", w.queue.name), log.String("job", job.String()), log.Any("error", err))
+		}
+	}()
+}
+
+// run 执行 handler,panic 转为 error
+func (w *Worker) run(job *Job) (err error) {
+	// 启动后 handlers 只读,无需加锁
+	h, ok := w.handlers[job.Type]
+	if !ok {
+		return ErrNoHandler
+	}
+
+	traceID := job.TraceID
+	if traceID == "" {
+		traceID = uuid.New().String()
+	}
+	ctx := icontext.WithContext(w.jobCtx)
+	ctx.SetMeta("traceId", traceID)
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
+
+// fail 处理失败:未超过重试次数时按退避排入延迟队列,否则移入死信队列
+func (w *Worker) fail(ctx context.Context, job *Job, cause error) error {
+	job.Attempts++
+	job.LastError = cause.Error()
+	fields := []log.Field{log.String("queue", w.queue.name), log.String("job", job.String()),
+		log.String("traceId", job.TraceID), log.Any("error", cause)}
+
+	if errors.Is(cause, ErrNoHandler) || job.Attempts > w.opts.maxRetries {
+		log.Error("队列任务失败,已移入死信队列", fields...)
+		return w.queue.bury(ctx, job)
+	}
+	delay := w.opts.backoff(job.Attempts)
+	log.Warn("队列任务失败,稍后重试", append(fields, log.Any("retryIn", delay.String()))...)
+	return w.queue.retry(ctx, job, delay)
+}
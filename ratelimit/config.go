@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	"github.com/aichy126/igo/config"
+	"github.com/gin-gonic/gin"
+)
+
+// 限流算法
+const (
+	AlgorithmSlidingWindow = "sliding_window"
+	AlgorithmTokenBucket   = "token_bucket"
+)
+
+// ruleConfig [ratelimit.xxx] 配置
+type ruleConfig struct {
+	Algorithm string  `mapstructure:"algorithm"` // sliding_window(默认)/token_bucket
+	Redis     string  `mapstructure:"redis"`     // [redis.xxx] 配置名,为空只使用本地限流
+	Limit     int     `mapstructure:"limit"`     // sliding_window:窗口内最多请求数
+	Window    int     `mapstructure:"window"`    // sliding_window:窗口长度,毫秒
+	Rate      float64 `mapstructure:"rate"`      // token_bucket:每秒补充令牌数
+	Burst     int     `mapstructure:"burst"`     // token_bucket:桶容量
+	Key       string  `mapstructure:"key"`       // ip(默认)/header:<名称>/user:<gin context key>
+	Fallback  *bool   `mapstructure:"fallback"`  // Redis 不可用时是否降级为本地限流,默认 true
+}
+
+// Rule 一条限流规则
+type Rule struct {
+	Name    string
+	Limiter Limiter
+	KeyFunc KeyFunc
+}
+
+// Middleware 返回该规则的 gin 中间件
+func (r *Rule) Middleware(opts ...MiddlewareOption) gin.HandlerFunc {
+	return Middleware(r.Limiter, r.KeyFunc, opts...)
+}
+
+// Rules 从配置加载的限流规则集合
+type Rules struct {
+	rules map[string]*Rule
+}
+
+// NewRules 从 [ratelimit.xxx] 配置加载所有限流规则,配置错误返回包含规则名的错误;
+// c 可为 nil(此时所有规则只使用本地限流)
+//
+//	[ratelimit.login]
+//	algorithm = "sliding_window"
+//	redis = "igorediskey"
+//	limit = 10
+//	window = 60000
+//	key = "ip"
+//
+//	rules, err := ratelimit.NewRules(app.Conf, app.Cache)
+//	r.POST("/login", rules.Middleware("login"), Login)
+func NewRules(conf *config.Config, c *cache.Cache) (*Rules, error) {
+	raw := make(map[string]*ruleConfig)
+	if err := conf.UnmarshalKey("ratelimit", &raw); err != nil {
+		return nil, fmt.Errorf("ratelimit 配置解析失败: %w", err)
+	}
+	rs := &Rules{rules: make(map[string]*Rule, len(raw))}
+	for name, rc := range raw {
+		rule, err := newRule(name, rc, c)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit 配置 [ratelimit.%s] 错误: %w", name, err)
+		}
+		rs.rules[name] = rule
+	}
+	return rs, nil
+}
+
+func newRule(name string, rc *ruleConfig, c *cache.Cache) (*Rule, error) {
+	keyFunc, err := parseKeyFunc(rc.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	var rds *cache.Redis
+	if rc.Redis != "" {
+		if c == nil || c.RedisManager == nil {
+			return nil, fmt.Errorf("未初始化缓存,无法使用 redis [%s]", rc.Redis)
+		}
+		if rds, err = c.Get(rc.Redis); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []Option{WithKeyPrefix(DefaultKeyPrefix + name + ":")}
+	if rc.Fallback != nil && !*rc.Fallback {
+		opts = append(opts, WithoutFallback())
+	}
+
+	var limiter Limiter
+	switch strings.ToLower(rc.Algorithm) {
+	case "", AlgorithmSlidingWindow:
+		if rc.Limit <= 0 || rc.Window <= 0 {
+			return nil, fmt.Errorf("sliding_window 需要配置正数的 limit 和 window(毫秒)")
+		}
+		limiter = NewSlidingWindow(rds, rc.Limit, time.Duration(rc.Window)*time.Millisecond, opts...)
+	case AlgorithmTokenBucket:
+		if rc.Rate <= 0 || rc.Burst <= 0 {
+			return nil, fmt.Errorf("token_bucket 需要配置正数的 rate 和 burst")
+		}
+		limiter = NewTokenBucket(rds, rc.Rate, rc.Burst, opts...)
+	default:
+		return nil, fmt.Errorf("不支持的 algorithm %q,可选 %s/%s", rc.Algorithm, AlgorithmSlidingWindow, AlgorithmTokenBucket)
+	}
+	return &Rule{Name: name, Limiter: limiter, KeyFunc: keyFunc}, nil
+}
+
+// parseKeyFunc 解析 key 配置:ip / header:<名称> / user:<gin context key>
+func parseKeyFunc(key string) (KeyFunc, error) {
+	kind, arg, _ := strings.Cut(strings.TrimSpace(key), ":")
+	switch kind {
+	case "", "ip":
+		return KeyByIP(), nil
+	case "header":
+		if arg == "" {
+			return nil, fmt.Errorf("key = %q 缺少请求头名称,如 header:X-Api-Key", key)
+		}
+		return KeyByHeader(arg), nil
+	case "user":
+		if arg == "" {
+			return nil, fmt.Errorf("key = %q 缺少 gin context key,如 user:uid", key)
+		}
+		return KeyByUser(arg), nil
+	default:
+		return nil, fmt.Errorf("不支持的 key %q,可选 ip/header:<名称>/user:<key>", key)
+	}
+}
+
+// Get 按名称获取规则
+func (rs *Rules) Get(name string) (*Rule, bool) {
+	r, ok := rs.rules[name]
+	return r, ok
+}
+
+// Middleware 返回指定规则的 gin 中间件
+// 规则不存在时 panic 并给出明确提示(路由注册阶段调用,走到这里只可能是配置名写错,尽早暴露)
+func (rs *Rules) Middleware(name string, opts ...MiddlewareOption) gin.HandlerFunc {
+	r, ok := rs.rules[name]
+	if !ok {
+		panic(fmt.Sprintf("限流规则 [%s] 不存在,请检查配置文件中的 [ratelimit.%s] 配置", name, name))
+	}
+	return r.Middleware(opts...)
+}
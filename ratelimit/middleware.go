@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/aichy126/igo/log"
+	"github.com/aichy126/igo/res"
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc 从请求中提取限流 key;返回空字符串表示该请求不参与限流
+type KeyFunc func(c *gin.Context) string
+
+// KeyByIP 按客户端 IP 限流
+func KeyByIP() KeyFunc {
+	return func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	}
+}
+
+// KeyByHeader 按请求头限流(如 X-Api-Key),请求头为空时不限流
+func KeyByHeader(name string) KeyFunc {
+	return func(c *gin.Context) string {
+		if v := c.GetHeader(name); v != "" {
+			return "header:" + name + ":" + v
+		}
+		return ""
+	}
+}
+
+// KeyByUser 按用户限流:读取鉴权中间件通过 c.Set(key, uid) 设置的用户标识,未登录时不限流
+func KeyByUser(key string) KeyFunc {
+	return func(c *gin.Context) string {
+		if v, ok := c.Get(key); ok {
+			if s := fmt.Sprint(v); s != "" {
+				return "user:" + s
+			}
+		}
+		return ""
+	}
+}
+
+// RejectHandler 请求被限流时的响应处理
+type RejectHandler func(c *gin.Context, result Result)
+
+// defaultReject 返回 429 + 统一 JSON 响应格式
+func defaultReject(c *gin.Context, _ Result) {
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, res.Body{Code: res.CodeFail, Msg: "请求过于频繁,请稍后再试"})
+}
+
+type middlewareOptions struct {
+	reject RejectHandler
+}
+
+// MiddlewareOption 中间件配置项
+type MiddlewareOption func(*middlewareOptions)
+
+// WithRejectHandler 自定义被限流时的响应(需自行调用 c.Abort*)
+func WithRejectHandler(h RejectHandler) MiddlewareOption {
+	return func(o *middlewareOptions) { o.reject = h }
+}
+
+// Middleware 限流中间件:写入 X-RateLimit-* 响应头,被限流时返回 429 并带 Retry-After
+// 限流器本身出错(已关闭本地降级且 Redis 不可用)时放行请求并记录日志
+func Middleware(limiter Limiter, keyFunc KeyFunc, opts ...MiddlewareOption) gin.HandlerFunc {
+	o := middlewareOptions{reject: defaultReject}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+		result, err := limiter.Allow(c.Request.Context(), key)
+		if err != nil {
+			log.Error("限流判定失败,放行请求", log.String("key", key), log.String("traceId", c.GetString("traceId")), log.Any("error", err))
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			o.reject(c, result)
+			return
+		}
+		c.Next()
+	}
+}
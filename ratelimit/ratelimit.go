@@ -0,0 +1,105 @@
+// Package ratelimit 提供基于 Redis 的分布式限流(滑动窗口、令牌桶),
+// 判定逻辑用 Lua 脚本原子执行,多个 pod 共享同一份计数;
+// Redis 不可用时自动降级为进程内限流(每个 pod 各自计数),不会因为限流组件故障拒绝全部请求。
+//
+// 基本用法:
+//
+//	rds, _ := igo.App.Cache.Get("igorediskey")
+//	limiter := ratelimit.NewSlidingWindow(rds, 100, time.Minute)
+//	r.POST("/login", ratelimit.Middleware(limiter, ratelimit.KeyByIP()), Login)
+//
+// 也可以通过 [ratelimit.xxx] 配置定义规则,见 NewRules。
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/aichy126/igo/log"
+	"github.com/google/uuid"
+)
+
+// DefaultKeyPrefix Redis 中限流 key 的默认前缀
+const DefaultKeyPrefix = "igo:ratelimit:"
+
+// Result 一次限流判定的结果
+type Result struct {
+	Allowed    bool          // 是否放行
+	Limit      int           // 限额(滑动窗口为窗口内请求数,令牌桶为桶容量)
+	Remaining  int           // 剩余额度
+	RetryAfter time.Duration // 被拒绝时建议的等待时间
+}
+
+// Limiter 限流器,并发安全
+type Limiter interface {
+	// Allow 对 key 消耗一次额度并返回判定结果
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+type options struct {
+	prefix   string
+	fallback bool
+}
+
+// Option 限流器配置项
+type Option func(*options)
+
+// WithKeyPrefix 设置 Redis key 前缀(默认 "igo:ratelimit:"),不同规则应使用不同前缀
+func WithKeyPrefix(prefix string) Option {
+	return func(o *options) { o.prefix = prefix }
+}
+
+// WithoutFallback 关闭本地降级:Redis 出错时 Allow 直接返回错误
+func WithoutFallback() Option {
+	return func(o *options) { o.fallback = false }
+}
+
+func newOptions(opts []Option) options {
+	o := options{prefix: DefaultKeyPrefix, fallback: true}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// fallbackLogger Redis 出错降级时记录日志,每分钟最多一条,避免故障期间刷屏
+type fallbackLogger struct {
+	last atomic.Int64
+}
+
+func (f *fallbackLogger) log(prefix string, err error) {
+	now := time.Now().UnixNano()
+	last := f.last.Load()
+	if now-last < int64(time.Minute) || !f.last.CompareAndSwap(last, now) {
+		return
+	}
+	log.Warn("限流 Redis 不可用,降级为本地限流", log.String("prefix", prefix), log.Any("error", err))
+}
+
+// sweeper 进程内限流的过期 key 清理:每隔 interval 最多清理一次,避免 key 无限增长
+// 非并发安全,由调用方的锁保护
+type sweeper struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// due 是否到了清理时间
+func (s *sweeper) due(now time.Time) bool {
+	if now.Sub(s.last) < s.interval {
+		return false
+	}
+	s.last = now
+	return true
+}
+
+// 滑动窗口有序集合的成员名需要跨 pod 唯一:进程 ID + 时间戳 + 自增序号
+var (
+	instanceID = uuid.New().String()[:8]
+	memberSeq  atomic.Uint64
+)
+
+func uniqueMember(now time.Time) string {
+	return instanceID + "-" + strconv.FormatInt(now.UnixMicro(), 36) + "-" + strconv.FormatUint(memberSeq.Add(1), 36)
+}
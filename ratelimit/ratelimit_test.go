@@ -0,0 +1,210 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	"github.com/aichy126/igo/config"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T, mr *miniredis.Miniredis) *cache.Redis {
+	t.Helper()
+	options := &redis.UniversalOptions{Addrs: []string{mr.Addr()}, MaxRetries: -1}
+	r := cache.NewRedis(redis.NewClient(options.Simple()), options)
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+func allowN(t *testing.T, l Limiter, key string, n int) (allowed int, last Result) {
+	t.Helper()
+	for range n {
+		res, err := l.Allow(t.Context(), key)
+		if err != nil {
+			t.Fatalf("Allow error: %v", err)
+		}
+		if res.Allowed {
+			allowed++
+		}
+		last = res
+	}
+	return allowed, last
+}
+
+func TestSlidingWindowRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	podA := NewSlidingWindow(newTestRedis(t, mr), 5, time.Minute)
+	podB := NewSlidingWindow(newTestRedis(t, mr), 5, time.Minute)
+
+	// 两个 pod 共享计数
+	if n, _ := allowN(t, podA, "u1", 3); n != 3 {
+		t.Fatalf("podA 放行 %d 次, want 3", n)
+	}
+	n, last := allowN(t, podB, "u1", 3)
+	if n != 2 || last.Allowed || last.RetryAfter <= 0 {
+		t.Errorf("podB 放行 %d 次, last=%+v, want 2 且最后一次被拒绝", n, last)
+	}
+	if n, _ := allowN(t, podA, "u2", 1); n != 1 {
+		t.Error("不同 key 互不影响")
+	}
+	if !mr.Exists(DefaultKeyPrefix + "u1") {
+		t.Error("redis 中应存在限流 key")
+	}
+}
+
+func TestTokenBucketRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	l := NewTokenBucket(newTestRedis(t, mr), 10, 3)
+
+	n, last := allowN(t, l, "k", 5)
+	if n != 3 || last.Allowed {
+		t.Fatalf("突发放行 %d 次, want 3", n)
+	}
+	time.Sleep(150 * time.Millisecond) // 10/s 的速率,150ms 后至少补充 1 个令牌
+	if n, _ := allowN(t, l, "k", 1); n != 1 {
+		t.Error("补充令牌后应放行")
+	}
+}
+
+// TestFallbackToLocal 验证 Redis 不可用时降级为本地限流,关闭降级时返回错误
+func TestFallbackToLocal(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rds := newTestRedis(t, mr)
+	withFallback := NewSlidingWindow(rds, 2, time.Minute)
+	noFallback := NewSlidingWindow(rds, 2, time.Minute, WithoutFallback())
+	mr.Close()
+
+	n, last := allowN(t, withFallback, "k", 3)
+	if n != 2 || last.Allowed {
+		t.Errorf("降级后放行 %d 次, want 2", n)
+	}
+	if _, err := noFallback.Allow(t.Context(), "k"); err == nil {
+		t.Error("关闭降级时 Redis 出错应返回错误")
+	}
+}
+
+func TestLocalLimiters(t *testing.T) {
+	sw := NewLocalSlidingWindow(2, 30*time.Millisecond)
+	if n, _ := allowN(t, sw, "k", 3); n != 2 {
+		t.Errorf("滑动窗口放行 %d 次, want 2", n)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if n, _ := allowN(t, sw, "k", 1); n != 1 {
+		t.Error("窗口滑过后应放行")
+	}
+
+	tb := NewLocalTokenBucket(1, 2)
+	n, last := allowN(t, tb, "k", 3)
+	if n != 2 || last.RetryAfter <= 0 || last.RetryAfter > time.Second {
+		t.Errorf("令牌桶放行 %d 次, retryAfter=%s", n, last.RetryAfter)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", Middleware(NewLocalSlidingWindow(1, time.Minute), KeyByHeader("X-Api-Key")), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	do := func(apiKey string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if apiKey != "" {
+			req.Header.Set("X-Api-Key", apiKey)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("a"); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("首次请求 code=%d headers=%v", w.Code, w.Header())
+	}
+	if w := do("a"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("超限请求 code=%d headers=%v", w.Code, w.Header())
+	}
+	if w := do("b"); w.Code != http.StatusOK {
+		t.Error("不同 key 不应受影响")
+	}
+	// key 为空的请求不参与限流
+	for range 3 {
+		if w := do(""); w.Code != http.StatusOK {
+			t.Fatal("无 key 的请求不应被限流")
+		}
+	}
+}
+
+func TestNewRules(t *testing.T) {
+	mr := miniredis.RunT(t)
+	path := filepath.Join(t.TempDir(), "config.toml")
+	content := fmt.Sprintf(`
+[redis.rl]
+address = %q
+probe_interval = -1
+
+[ratelimit.login]
+redis = "rl"
+limit = 1
+window = 60000
+
+[ratelimit.api]
+algorithm = "token_bucket"
+rate = 10
+burst = 20
+key = "header:X-Api-Key"
+`, mr.Addr())
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := config.NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cache.NewCache(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	rules, err := NewRules(conf, c)
+	if err != nil {
+		t.Fatalf("NewRules error: %v", err)
+	}
+	login, ok := rules.Get("login")
+	if !ok {
+		t.Fatal("缺少 login 规则")
+	}
+	if n, _ := allowN(t, login.Limiter, "ip:1.2.3.4", 2); n != 1 {
+		t.Errorf("login 规则放行 %d 次, want 1", n)
+	}
+	if !mr.Exists(DefaultKeyPrefix + "login:ip:1.2.3.4") {
+		t.Error("规则的 redis key 应带规则名前缀")
+	}
+	if _, ok := rules.Get("api"); !ok {
+		t.Error("缺少 api 规则")
+	}
+}
+
+func TestRuleConfigErrors(t *testing.T) {
+	cases := map[string]*ruleConfig{
+		"缺少 limit":   {Window: 1000},
+		"未知算法":       {Algorithm: "leaky", Limit: 1, Window: 1},
+		"令牌桶缺 rate":  {Algorithm: AlgorithmTokenBucket, Burst: 1},
+		"非法 key":     {Limit: 1, Window: 1, Key: "cookie:sid"},
+		"header 缺名称": {Limit: 1, Window: 1, Key: "header:"},
+		"redis 未初始化": {Limit: 1, Window: 1, Redis: "x"},
+	}
+	for name, rc := range cases {
+		if _, err := newRule("r", rc, nil); err == nil {
+			t.Errorf("%s: 应返回错误", name)
+		}
+	}
+}
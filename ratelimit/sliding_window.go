@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript 滑动窗口(请求日志):有序集合记录窗口内每次请求的时间戳
+// KEYS[1] 限流 key;ARGV: 当前时间(毫秒)、窗口(毫秒)、限额、成员名
+// 返回 {是否放行, 剩余额度, 建议等待毫秒}
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[4])
+	redis.call('PEXPIRE', key, window)
+	return {1, limit - count - 1, 0}
+end
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local retry = window
+if oldest[2] then
+	retry = tonumber(oldest[2]) + window - now
+end
+return {0, 0, retry}
+`)
+
+// SlidingWindow 基于 Redis 的滑动窗口限流:任意 window 长度的时间段内最多放行 limit 次
+type SlidingWindow struct {
+	redis  *cache.Redis
+	limit  int
+	window time.Duration
+	opts   options
+	local  *LocalSlidingWindow
+	logger fallbackLogger
+}
+
+// NewSlidingWindow 创建滑动窗口限流器;r 为 nil 时只使用本地限流
+func NewSlidingWindow(r *cache.Redis, limit int, window time.Duration, opts ...Option) *SlidingWindow {
+	return &SlidingWindow{
+		redis:  r,
+		limit:  limit,
+		window: window,
+		opts:   newOptions(opts),
+		local:  NewLocalSlidingWindow(limit, window),
+	}
+}
+
+// Allow 实现 Limiter
+func (s *SlidingWindow) Allow(ctx context.Context, key string) (Result, error) {
+	if s.redis == nil {
+		return s.local.Allow(ctx, key)
+	}
+	now := time.Now()
+	vals, err := slidingWindowScript.Run(ctx, s.redis, []string{s.opts.prefix + key},
+		now.UnixMilli(), s.window.Milliseconds(), s.limit, uniqueMember(now)).Int64Slice()
+	if err != nil {
+		if !s.opts.fallback {
+			return Result{}, err
+		}
+		s.logger.log(s.opts.prefix, err)
+		return s.local.Allow(ctx, key)
+	}
+	return Result{
+		Allowed:    vals[0] == 1,
+		Limit:      s.limit,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+	}, nil
+}
+
+// LocalSlidingWindow 进程内滑动窗口限流,用于 Redis 降级和单机场景
+type LocalSlidingWindow struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	events  map[string][]time.Time
+	sweeper sweeper
+}
+
+// NewLocalSlidingWindow 创建进程内滑动窗口限流器
+func NewLocalSlidingWindow(limit int, window time.Duration) *LocalSlidingWindow {
+	return &LocalSlidingWindow{
+		limit:   limit,
+		window:  window,
+		events:  make(map[string][]time.Time),
+		sweeper: sweeper{interval: max(window, time.Minute)},
+	}
+}
+
+// Allow 实现 Limiter
+func (l *LocalSlidingWindow) Allow(_ context.Context, key string) (Result, error) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.sweeper.due(now) {
+		for k, evs := range l.events {
+			if len(evs) == 0 || now.Sub(evs[len(evs)-1]) >= l.window {
+				delete(l.events, k)
+			}
+		}
+	}
+
+	evs := l.events[key]
+	i := 0
+	for i < len(evs) && now.Sub(evs[i]) >= l.window {
+		i++
+	}
+	evs = evs[i:]
+
+	if len(evs) >= l.limit {
+		l.events[key] = evs
+		retry := l.window
+		if len(evs) > 0 {
+			retry = evs[0].Add(l.window).Sub(now)
+		}
+		return Result{Allowed: false, Limit: l.limit, Remaining: 0, RetryAfter: retry}, nil
+	}
+	l.events[key] = append(evs, now)
+	return Result{Allowed: true, Limit: l.limit, Remaining: l.limit - len(evs) - 1}, nil
+}
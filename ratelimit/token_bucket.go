@@ -0,0 +1,140 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript 令牌桶:hash 保存当前令牌数和上次补充时间
+// KEYS[1] 限流 key;ARGV: 当前时间(毫秒)、每秒补充令牌数、桶容量
+// 返回 {是否放行, 剩余令牌(取整), 建议等待毫秒}
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local burst = tonumber(ARGV[3])
+
+local data = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(data[1]) or burst
+local ts = tonumber(data[2]) or now
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) / 1000 * rate)
+end
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate * 1000)
+end
+
+redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', tostring(math.max(now, ts)))
+redis.call('PEXPIRE', key, math.ceil(burst / rate * 1000) + 1000)
+return {allowed, math.floor(tokens), retry}
+`)
+
+// TokenBucket 基于 Redis 的令牌桶限流:平均每秒放行 rate 次,允许 burst 次突发
+type TokenBucket struct {
+	redis  *cache.Redis
+	rate   float64
+	burst  int
+	opts   options
+	local  *LocalTokenBucket
+	logger fallbackLogger
+}
+
+// NewTokenBucket 创建令牌桶限流器;r 为 nil 时只使用本地限流
+func NewTokenBucket(r *cache.Redis, rate float64, burst int, opts ...Option) *TokenBucket {
+	return &TokenBucket{
+		redis: r,
+		rate:  rate,
+		burst: burst,
+		opts:  newOptions(opts),
+		local: NewLocalTokenBucket(rate, burst),
+	}
+}
+
+// Allow 实现 Limiter
+func (t *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	if t.redis == nil {
+		return t.local.Allow(ctx, key)
+	}
+	vals, err := tokenBucketScript.Run(ctx, t.redis, []string{t.opts.prefix + key},
+		time.Now().UnixMilli(), t.rate, t.burst).Int64Slice()
+	if err != nil {
+		if !t.opts.fallback {
+			return Result{}, err
+		}
+		t.logger.log(t.opts.prefix, err)
+		return t.local.Allow(ctx, key)
+	}
+	return Result{
+		Allowed:    vals[0] == 1,
+		Limit:      t.burst,
+		Remaining:  int(vals[1]),
+		RetryAfter: time.Duration(vals[2]) * time.Millisecond,
+	}, nil
+}
+
+// LocalTokenBucket 进程内令牌桶限流,用于 Redis 降级和单机场景
+type LocalTokenBucket struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   int
+	buckets map[string]*bucket
+	sweeper sweeper
+}
+
+type bucket struct {
+	tokens float64
+	ts     time.Time
+}
+
+// NewLocalTokenBucket 创建进程内令牌桶限流器
+func NewLocalTokenBucket(rate float64, burst int) *LocalTokenBucket {
+	return &LocalTokenBucket{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*bucket),
+		sweeper: sweeper{interval: time.Minute},
+	}
+}
+
+// Allow 实现 Limiter
+func (l *LocalTokenBucket) Allow(_ context.Context, key string) (Result, error) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 已经补满的桶与新建的桶等价,可以直接删除
+	fullAfter := time.Duration(float64(l.burst) / l.rate * float64(time.Second))
+	if l.sweeper.due(now) {
+		for k, b := range l.buckets {
+			if now.Sub(b.ts) >= fullAfter {
+				delete(l.buckets, k)
+			}
+		}
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), ts: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.ts).Seconds()*l.rate)
+	b.ts = now
+
+	if b.tokens < 1 {
+		retry := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return Result{Allowed: false, Limit: l.burst, Remaining: 0, RetryAfter: retry}, nil
+	}
+	b.tokens--
+	return Result{Allowed: true, Limit: l.burst, Remaining: int(b.tokens)}, nil
+}
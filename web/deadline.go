@@ -0,0 +1,42 @@
+package web
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	icontext "github.com/aichy126/igo/context"
+	"github.com/gin-gonic/gin"
+)
+
+// DeadlineHeader 上游透传的剩余超时(毫秒),与 httpclient.DeadlineHeader 一致
+const DeadlineHeader = "X-Request-Deadline"
+
+// Deadline 读取上游透传的剩余超时,为请求设置截止时间:
+// c.Request.Context() 和 context.Ginform(c) 得到的 igo context 都带上该截止时间,
+// 调用方已放弃等待后,下游的 db/redis/httpclient 调用会尽早返回,httpclient 也会继续向下透传。
+// 请求没有该 header 时不做处理。NewWeb 默认注册
+func Deadline() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v := c.GetHeader(DeadlineHeader)
+		if v == "" {
+			c.Next()
+			return
+		}
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms <= 0 {
+			c.Next()
+			return
+		}
+		deadline := time.Now().Add(time.Duration(ms) * time.Millisecond)
+
+		// igo context 不继承请求 context(客户端断开不取消),这里只附加截止时间
+		dctx, cancel := context.WithDeadline(context.Background(), deadline)
+		defer cancel()
+		c.Set(icontext.DeadlineContextKey, dctx)
+		rctx, rcancel := context.WithDeadline(c.Request.Context(), deadline)
+		defer rcancel()
+		c.Request = c.Request.WithContext(rctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	icontext "github.com/aichy126/igo/context"
+	"github.com/gin-gonic/gin"
+)
+
+func TestDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Deadline())
+	var remaining, reqRemaining time.Duration
+	var ok bool
+	r.GET("/", func(c *gin.Context) {
+		ctx := icontext.Ginform(c)
+		var deadline time.Time
+		deadline, ok = ctx.Deadline()
+		remaining = time.Until(deadline)
+		if d, has := c.Request.Context().Deadline(); has {
+			reqRemaining = time.Until(d)
+		}
+	})
+
+	do := func(v string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if v != "" {
+			req.Header.Set(DeadlineHeader, v)
+		}
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	do("500")
+	if !ok || remaining <= 0 || remaining > 500*time.Millisecond {
+		t.Errorf("igo context 截止时间 ok=%v remaining=%v", ok, remaining)
+	}
+	if reqRemaining <= 0 || reqRemaining > 500*time.Millisecond {
+		t.Errorf("请求 context 剩余时间 = %v", reqRemaining)
+	}
+
+	for _, v := range []string{"", "abc", "-1"} {
+		do(v)
+		if ok {
+			t.Errorf("header %q 不应设置截止时间", v)
+		}
+	}
+}
@@ -37,6 +37,7 @@ func NewWeb(conf *config.Config) (*Web, error) {
 	// recovery 始终开启(panic 记录到 zap 日志),避免 gin 默认 logger 与 access 日志双写
 	web.Router = gin.New()
 	web.Router.Use(AddTraceId())
+	web.Router.Use(Deadline())
 	web.Router.Use(log.RecoveryWithZap(true))
 	if Debug {
 		// debug 模式下保留 gin 控制台请求日志,方便本地开发
@@ -0,0 +1,303 @@
+package session
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aichy126/igo/log"
+	"github.com/gin-gonic/gin"
+)
+
+// 默认配置
+const (
+	DefaultCookieName = "igo_session"
+	DefaultMaxAge     = 24 * time.Hour
+)
+
+type options struct {
+	cookieName string
+	maxAge     time.Duration
+	path       string
+	domain     string
+	secure     bool
+	sameSite   http.SameSite
+	oldSecrets [][]byte
+	encryptKey []byte
+}
+
+// Option 中间件配置项
+type Option func(*options)
+
+// WithCookieName 设置 cookie 名(默认 "igo_session")
+func WithCookieName(name string) Option {
+	return func(o *options) { o.cookieName = name }
+}
+
+// WithMaxAge 设置空闲过期时间(默认 24h):会话超过该时间没有请求即失效,每次请求重新计时
+func WithMaxAge(d time.Duration) Option {
+	return func(o *options) { o.maxAge = d }
+}
+
+// WithPath 设置 cookie 的 Path(默认 "/")
+func WithPath(path string) Option {
+	return func(o *options) { o.path = path }
+}
+
+// WithDomain 设置 cookie 的 Domain
+func WithDomain(domain string) Option {
+	return func(o *options) { o.domain = domain }
+}
+
+// WithSecure 设置 cookie 是否只在 HTTPS 下发送(默认 true),本地 http 开发时可关闭
+func WithSecure(secure bool) Option {
+	return func(o *options) { o.secure = secure }
+}
+
+// WithSameSite 设置 cookie 的 SameSite(默认 Lax)
+func WithSameSite(sameSite http.SameSite) Option {
+	return func(o *options) { o.sameSite = sameSite }
+}
+
+// WithOldSecrets 轮换签名密钥时配置旧密钥:旧密钥签名的 cookie 仍然有效,下次写 cookie 时改用新密钥
+func WithOldSecrets(secrets ...[]byte) Option {
+	return func(o *options) { o.oldSecrets = secrets }
+}
+
+// WithEncryption 使用 AES-GCM 加密 cookie 中的会话 ID,key 长度为 16/24/32 字节
+func WithEncryption(key []byte) Option {
+	return func(o *options) { o.encryptKey = key }
+}
+
+// manager 一个中间件实例的配置与 cookie 编解码
+type manager struct {
+	store Store
+	o     options
+	keys  [][]byte // 签名密钥,第一个用于签名,全部用于校验
+	aead  cipher.AEAD
+}
+
+// Middleware 会话中间件。secret 为 HMAC 签名密钥,至少 32 字节;配置错误时 panic(启动即暴露)。
+// 会话在响应头写出前自动保存,handler 中无需手动调用保存
+func Middleware(store Store, secret []byte, opts ...Option) gin.HandlerFunc {
+	o := options{
+		cookieName: DefaultCookieName,
+		maxAge:     DefaultMaxAge,
+		path:       "/",
+		secure:     true,
+		sameSite:   http.SameSiteLaxMode,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(secret) < 32 {
+		panic("session: secret 至少 32 字节")
+	}
+	if o.maxAge <= 0 {
+		panic("session: max_age 必须大于 0")
+	}
+	m := &manager{store: store, o: o, keys: append([][]byte{secret}, o.oldSecrets...)}
+	if o.encryptKey != nil {
+		block, err := aes.NewCipher(o.encryptKey)
+		if err != nil {
+			panic(fmt.Sprintf("session: 加密密钥无效: %v", err))
+		}
+		m.aead, _ = cipher.NewGCM(block)
+	}
+	return m.handle
+}
+
+func (m *manager) handle(c *gin.Context) {
+	s := m.load(c)
+	c.Set(ginContextKey, s)
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), ctxKey{}, s))
+
+	w := &sessionWriter{ResponseWriter: c.Writer}
+	w.commit = func() { m.commit(c, w.ResponseWriter, s) }
+	c.Writer = w
+	c.Next()
+	// handler 没有写响应体时,在 gin 写出响应头之前保存
+	w.commitOnce()
+}
+
+// load 根据 cookie 加载会话;cookie 无效、会话已过期或存储出错时新建会话
+func (m *manager) load(c *gin.Context) *Session {
+	value, err := c.Cookie(m.o.cookieName)
+	if err != nil || value == "" {
+		return newSession()
+	}
+	id, ok := m.decode(value)
+	if !ok {
+		return newSession()
+	}
+	data, err := m.store.Load(c.Request.Context(), id)
+	if err != nil {
+		log.Error("加载会话失败", log.String("traceId", c.GetString("traceId")), log.Any("error", err))
+		return newSession()
+	}
+	if data == nil {
+		return newSession()
+	}
+	s := &Session{id: id}
+	if err := json.Unmarshal(data, &s.values); err != nil || s.values == nil {
+		log.Warn("会话数据损坏,已重建", log.String("traceId", c.GetString("traceId")), log.Any("error", err))
+		return newSession()
+	}
+	return s
+}
+
+// commit 保存会话并写 Set-Cookie,w 为未包装的 writer
+func (m *manager) commit(c *gin.Context, w http.ResponseWriter, s *Session) {
+	ctx := c.Request.Context()
+	s.mu.Lock()
+	id, oldID := s.id, s.oldID
+	isNew, modified, destroyed, empty := s.isNew, s.modified, s.destroyed, len(s.values) == 0
+	s.mu.Unlock()
+
+	var err error
+	switch {
+	case destroyed:
+		if !isNew {
+			err = m.store.Delete(ctx, id)
+		}
+		if oldID != "" {
+			err = errors.Join(err, m.store.Delete(ctx, oldID))
+		}
+		if !isNew || oldID != "" {
+			m.setCookie(w, "", -1)
+		}
+	case isNew && empty:
+		// 匿名请求不创建会话
+	case modified || isNew:
+		var data []byte
+		if data, err = s.encode(); err == nil {
+			err = m.store.Save(ctx, id, data, m.o.maxAge)
+		}
+		if err == nil && oldID != "" {
+			err = m.store.Delete(ctx, oldID)
+		}
+		if err == nil {
+			m.setCookie(w, m.encode(id), int(m.o.maxAge/time.Second))
+		}
+	default:
+		// 滑动过期:未修改的会话只刷新过期时间
+		if err = m.store.Touch(ctx, id, m.o.maxAge); err == nil {
+			m.setCookie(w, m.encode(id), int(m.o.maxAge/time.Second))
+		}
+	}
+	if err != nil {
+		log.Error("保存会话失败", log.String("traceId", c.GetString("traceId")), log.Any("error", err))
+	}
+}
+
+func (m *manager) setCookie(w http.ResponseWriter, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.o.cookieName,
+		Value:    value,
+		Path:     m.o.path,
+		Domain:   m.o.domain,
+		MaxAge:   maxAge,
+		Secure:   m.o.secure,
+		HttpOnly: true,
+		SameSite: m.o.sameSite,
+	})
+}
+
+// encode 会话 ID 编码为 cookie 值:payload + "." + base64(HMAC(cookie 名 + payload)),
+// payload 为会话 ID,开启加密时为 base64(nonce + AES-GCM 密文)
+func (m *manager) encode(id string) string {
+	p := id
+	if m.aead != nil {
+		nonce := make([]byte, m.aead.NonceSize())
+		_, _ = rand.Read(nonce)
+		p = base64.RawURLEncoding.EncodeToString(m.aead.Seal(nonce, nonce, []byte(id), []byte(m.o.cookieName)))
+	}
+	return p + "." + base64.RawURLEncoding.EncodeToString(m.sign(m.keys[0], p))
+}
+
+// decode 校验签名并解出会话 ID
+func (m *manager) decode(value string) (string, bool) {
+	p, sig, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", false
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return "", false
+	}
+	valid := false
+	for _, key := range m.keys {
+		if hmac.Equal(mac, m.sign(key, p)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", false
+	}
+	if m.aead == nil {
+		return p, true
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	n := m.aead.NonceSize()
+	if err != nil || len(payload) < n {
+		return "", false
+	}
+	id, err := m.aead.Open(nil, payload[:n], payload[n:], []byte(m.o.cookieName))
+	if err != nil {
+		return "", false
+	}
+	return string(id), true
+}
+
+func (m *manager) sign(key []byte, payload string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(m.o.cookieName))
+	h.Write([]byte{0})
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
+// sessionWriter 在响应头写出前保存会话,保证 Set-Cookie 能写进响应头
+type sessionWriter struct {
+	gin.ResponseWriter
+	commit    func()
+	committed bool
+}
+
+func (w *sessionWriter) commitOnce() {
+	if !w.committed {
+		w.committed = true
+		w.commit()
+	}
+}
+
+func (w *sessionWriter) WriteHeaderNow() {
+	w.commitOnce()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *sessionWriter) Write(data []byte) (int, error) {
+	w.commitOnce()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *sessionWriter) WriteString(s string) (int, error) {
+	w.commitOnce()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *sessionWriter) Flush() {
+	w.commitOnce()
+	w.ResponseWriter.Flush()
+}
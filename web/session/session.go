@@ -0,0 +1,191 @@
+// Package session 基于 cookie + 服务端存储的登录会话 gin 中间件。
+//
+// cookie 中只保存经 HMAC 签名(可选 AES-GCM 加密)的会话 ID,会话数据存放在 Store 中
+// (生产环境用 RedisStore,测试用 MemoryStore);每次请求自动续期(滑动过期)。
+//
+//	app.Web.Router.Use(session.Middleware(session.NewRedisStore(rds), secret))
+//
+//	func Login(c *gin.Context) {
+//		s := session.Default(c)
+//		s.Regenerate() // 登录后更换会话 ID,防止会话固定攻击
+//		s.Set("uid", uid)
+//	}
+//
+//	uid, ok := session.Value[int64](ctx, "uid") // ctx 为 igo context.IContext
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+
+	icontext "github.com/aichy126/igo/context"
+	"github.com/gin-gonic/gin"
+)
+
+// ginContextKey 会话在 gin.Context 中的 key
+const ginContextKey = "igo-session"
+
+// ctxKey 会话在请求 context 中的 key
+type ctxKey struct{}
+
+// Session 一次请求对应的会话,并发安全;修改在响应写出前自动保存
+type Session struct {
+	mu     sync.Mutex
+	id     string
+	oldID  string // Regenerate 前的 ID,保存时从存储中删除
+	values map[string]json.RawMessage
+
+	isNew       bool // 请求未携带有效会话,本次新建
+	modified    bool
+	destroyed   bool
+	regenerated bool
+}
+
+func newSession() *Session {
+	return &Session{id: newID(), values: make(map[string]json.RawMessage), isNew: true}
+}
+
+// newID 生成 256 位随机会话 ID
+func newID() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ID 会话 ID
+func (s *Session) ID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.id
+}
+
+// IsNew 是否为本次请求新建的会话(请求未携带有效会话 cookie 或会话已过期)
+func (s *Session) IsNew() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isNew
+}
+
+// Get 读取 key 并解码到 v,key 不存在时返回 false
+func (s *Session) Get(key string, v any) (bool, error) {
+	s.mu.Lock()
+	raw, ok := s.values[key]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return true, fmt.Errorf("会话值 %s 解码失败: %w", key, err)
+	}
+	return true, nil
+}
+
+// Set 设置 key,v 以 JSON 编码保存
+func (s *Session) Set(key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("会话值 %s 编码失败: %w", key, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = raw
+	s.modified = true
+	return nil
+}
+
+// Delete 删除 key
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		delete(s.values, key)
+		s.modified = true
+	}
+}
+
+// Clear 清空所有值,会话 ID 不变
+func (s *Session) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.values) > 0 {
+		s.values = make(map[string]json.RawMessage)
+		s.modified = true
+	}
+}
+
+// Regenerate 更换会话 ID,保留已有的值;旧 ID 在保存时删除。
+// 登录、提权等场景必须调用,防止会话固定攻击
+func (s *Session) Regenerate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.isNew && s.oldID == "" {
+		s.oldID = s.id
+	}
+	s.id = newID()
+	s.regenerated = true
+	s.modified = true
+}
+
+// Destroy 销毁会话(如退出登录):删除存储中的数据并让浏览器删除 cookie
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]json.RawMessage)
+	s.destroyed = true
+}
+
+// encode 序列化会话值
+func (s *Session) encode() ([]byte, error) {
+	s.mu.Lock()
+	values := maps.Clone(s.values)
+	s.mu.Unlock()
+	return json.Marshal(values)
+}
+
+// Default 获取 gin 请求中的会话,未使用 Middleware 时返回 nil
+func Default(c *gin.Context) *Session {
+	if v, ok := c.Get(ginContextKey); ok {
+		s, _ := v.(*Session)
+		return s
+	}
+	return nil
+}
+
+// From 从 context 中获取会话,支持 *gin.Context、igo 的 context.IContext(由 context.Ginform 创建)
+// 以及 c.Request.Context() 派生的 context;取不到时返回 nil
+func From(ctx context.Context) *Session {
+	if c, ok := ctx.(*gin.Context); ok {
+		return Default(c)
+	}
+	if s, ok := ctx.Value(ctxKey{}).(*Session); ok {
+		return s
+	}
+	if ic, ok := ctx.(icontext.IContext); ok {
+		if req := ic.GetHttpRequest(); req != nil {
+			s, _ := req.Context().Value(ctxKey{}).(*Session)
+			return s
+		}
+	}
+	return nil
+}
+
+// Value 从 context 的会话中按类型读取 key;没有会话、key 不存在或类型不符时返回零值和 false
+//
+//	uid, ok := session.Value[int64](ctx, "uid")
+func Value[T any](ctx context.Context, key string) (T, bool) {
+	var v T
+	s := From(ctx)
+	if s == nil {
+		return v, false
+	}
+	ok, err := s.Get(key, &v)
+	if !ok || err != nil {
+		return v, false
+	}
+	return v, true
+}
@@ -0,0 +1,228 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	icontext "github.com/aichy126/igo/context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestRouter(store Store, opts ...Option) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware(store, testSecret, opts...))
+	r.POST("/login", func(c *gin.Context) {
+		s := Default(c)
+		s.Regenerate()
+		_ = s.Set("uid", int64(42))
+		c.String(http.StatusOK, s.ID())
+	})
+	r.GET("/me", func(c *gin.Context) {
+		ctx := icontext.Ginform(c)
+		uid, ok := Value[int64](ctx, "uid")
+		if !ok {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"uid": uid, "id": From(ctx).ID()})
+	})
+	r.POST("/logout", func(c *gin.Context) {
+		Default(c).Destroy()
+		c.Status(http.StatusNoContent)
+	})
+	return r
+}
+
+// do 发起请求,cookie 不为空时带上
+func do(r *gin.Engine, method, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, c := range w.Result().Cookies() {
+		if c.Name == DefaultCookieName {
+			return c
+		}
+	}
+	t.Fatal("响应中没有会话 cookie")
+	return nil
+}
+
+func TestSessionFlow(t *testing.T) {
+	store := NewMemoryStore()
+	r := newTestRouter(store)
+
+	// 匿名请求不创建会话
+	w := do(r, "GET", "/me", nil)
+	if w.Code != http.StatusUnauthorized || len(w.Result().Cookies()) != 0 || store.Len() != 0 {
+		t.Fatalf("匿名请求: code=%d cookies=%v", w.Code, w.Result().Cookies())
+	}
+
+	w = do(r, "POST", "/login", nil)
+	cookie := sessionCookie(t, w)
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode || cookie.MaxAge != int(DefaultMaxAge/time.Second) {
+		t.Errorf("cookie 属性不正确: %+v", cookie)
+	}
+	if !strings.HasPrefix(cookie.Value, w.Body.String()+".") {
+		t.Error("未加密时 cookie 中应为签名后的会话 ID")
+	}
+
+	w = do(r, "GET", "/me", cookie)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"uid":42`) {
+		t.Fatalf("/me = %d %s", w.Code, w.Body.String())
+	}
+	// 未修改的会话每次请求续期
+	if c := sessionCookie(t, w); c.Value != cookie.Value {
+		t.Error("续期不应更换 cookie 值")
+	}
+
+	// 再次登录更换会话 ID,旧会话失效
+	w = do(r, "POST", "/login", cookie)
+	newCookie := sessionCookie(t, w)
+	if newCookie.Value == cookie.Value {
+		t.Fatal("登录后应更换会话 ID")
+	}
+	if do(r, "GET", "/me", cookie).Code != http.StatusUnauthorized {
+		t.Error("旧会话 ID 应失效")
+	}
+	if store.Len() != 1 {
+		t.Errorf("store 中应只有 1 个会话, got %d", store.Len())
+	}
+
+	w = do(r, "POST", "/logout", newCookie)
+	if c := sessionCookie(t, w); c.MaxAge >= 0 {
+		t.Errorf("退出后应删除 cookie: %+v", c)
+	}
+	if store.Len() != 0 || do(r, "GET", "/me", newCookie).Code != http.StatusUnauthorized {
+		t.Error("退出后会话应被删除")
+	}
+}
+
+func TestCookieTamper(t *testing.T) {
+	store := NewMemoryStore()
+	r := newTestRouter(store)
+	cookie := sessionCookie(t, do(r, "POST", "/login", nil))
+
+	forged := *cookie
+	forged.Value = "x" + cookie.Value[1:]
+	if do(r, "GET", "/me", &forged).Code != http.StatusUnauthorized {
+		t.Error("篡改的 cookie 应无效")
+	}
+
+	// 其他密钥签名的 cookie 无效;配置为旧密钥后仍然有效
+	newSecret := []byte("fedcba9876543210fedcba9876543210")
+	r2 := gin.New()
+	r2.Use(Middleware(store, newSecret))
+	r2.GET("/me", func(c *gin.Context) {
+		if _, ok := Value[int64](c, "uid"); !ok {
+			c.Status(http.StatusUnauthorized)
+		}
+	})
+	if do(r2, "GET", "/me", cookie).Code != http.StatusUnauthorized {
+		t.Error("密钥不同的 cookie 应无效")
+	}
+	r3 := gin.New()
+	r3.Use(Middleware(store, newSecret, WithOldSecrets(testSecret)))
+	r3.GET("/me", func(c *gin.Context) {
+		if _, ok := Value[int64](c, "uid"); !ok {
+			c.Status(http.StatusUnauthorized)
+		}
+	})
+	if do(r3, "GET", "/me", cookie).Code != http.StatusOK {
+		t.Error("旧密钥签名的 cookie 应有效")
+	}
+}
+
+func TestEncryptedCookie(t *testing.T) {
+	store := NewMemoryStore()
+	r := newTestRouter(store, WithEncryption([]byte("0123456789abcdef")), WithCookieName("sid"), WithSecure(false))
+	w := do(r, "POST", "/login", nil)
+	var cookie *http.Cookie
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "sid" {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Secure {
+		t.Fatalf("cookie = %+v", cookie)
+	}
+	if strings.Contains(cookie.Value, w.Body.String()) {
+		t.Error("加密后 cookie 中不应出现明文会话 ID")
+	}
+	if do(r, "GET", "/me", cookie).Code != http.StatusOK {
+		t.Error("加密 cookie 应能正常解出会话")
+	}
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	store := NewMemoryStore()
+	r := newTestRouter(store, WithMaxAge(150*time.Millisecond))
+	cookie := sessionCookie(t, do(r, "POST", "/login", nil))
+	for range 3 {
+		time.Sleep(80 * time.Millisecond)
+		if do(r, "GET", "/me", cookie).Code != http.StatusOK {
+			t.Fatal("持续访问时会话不应过期")
+		}
+	}
+	time.Sleep(200 * time.Millisecond)
+	if do(r, "GET", "/me", cookie).Code != http.StatusUnauthorized {
+		t.Error("空闲超过 max_age 后会话应过期")
+	}
+}
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rds := cache.NewRedis(redis.NewClient(&redis.Options{Addr: mr.Addr()}), nil)
+	defer rds.Close()
+	store := NewRedisStore(rds, WithKeyPrefix("s:"))
+	r := newTestRouter(store, WithMaxAge(time.Hour))
+
+	w := do(r, "POST", "/login", nil)
+	cookie := sessionCookie(t, w)
+	id := w.Body.String()
+	if !mr.Exists("s:" + id) {
+		t.Fatal("会话应保存到 redis")
+	}
+	if ttl := mr.TTL("s:" + id); ttl != time.Hour {
+		t.Errorf("TTL = %v", ttl)
+	}
+	mr.FastForward(30 * time.Minute)
+	if do(r, "GET", "/me", cookie).Code != http.StatusOK {
+		t.Fatal("redis 会话读取失败")
+	}
+	if ttl := mr.TTL("s:" + id); ttl != time.Hour {
+		t.Errorf("访问后 TTL 应刷新, got %v", ttl)
+	}
+}
+
+func TestMiddlewarePanics(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"密钥过短":   func() { Middleware(NewMemoryStore(), []byte("short")) },
+		"加密密钥无效": func() { Middleware(NewMemoryStore(), testSecret, WithEncryption([]byte("bad"))) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s 应 panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,143 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aichy126/igo/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultKeyPrefix RedisStore 默认 key 前缀
+const DefaultKeyPrefix = "igo:session:"
+
+// Store 会话数据存储
+type Store interface {
+	// Load 读取会话数据,不存在或已过期时返回 nil, nil
+	Load(ctx context.Context, id string) ([]byte, error)
+	// Save 保存会话数据,ttl 后过期
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	// Touch 刷新过期时间(滑动过期),数据不变
+	Touch(ctx context.Context, id string, ttl time.Duration) error
+	// Delete 删除会话
+	Delete(ctx context.Context, id string) error
+}
+
+// RedisStore 基于 Redis 的会话存储,多实例部署时共享会话
+type RedisStore struct {
+	rds    *cache.Redis
+	prefix string
+}
+
+// StoreOption RedisStore 配置项
+type StoreOption func(*RedisStore)
+
+// WithKeyPrefix 设置 Redis key 前缀(默认 "igo:session:")
+func WithKeyPrefix(prefix string) StoreOption {
+	return func(s *RedisStore) { s.prefix = prefix }
+}
+
+// NewRedisStore 创建 Redis 会话存储
+func NewRedisStore(r *cache.Redis, opts ...StoreOption) *RedisStore {
+	s := &RedisStore{rds: r, prefix: DefaultKeyPrefix}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *RedisStore) Load(ctx context.Context, id string) ([]byte, error) {
+	data, err := s.rds.Get(ctx, s.prefix+id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取会话失败: %w", err)
+	}
+	return data, nil
+}
+
+func (s *RedisStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	if err := s.rds.Set(ctx, s.prefix+id, data, ttl).Err(); err != nil {
+		return fmt.Errorf("保存会话失败: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	if err := s.rds.Expire(ctx, s.prefix+id, ttl).Err(); err != nil {
+		return fmt.Errorf("刷新会话过期时间失败: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	if err := s.rds.Del(ctx, s.prefix+id).Err(); err != nil {
+		return fmt.Errorf("删除会话失败: %w", err)
+	}
+	return nil
+}
+
+// MemoryStore 进程内会话存储,用于测试和单机开发;过期数据在读取时清理
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+type memoryItem struct {
+	data     []byte
+	expireAt time.Time
+}
+
+// NewMemoryStore 创建进程内会话存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: make(map[string]memoryItem)}
+}
+
+func (s *MemoryStore) Load(_ context.Context, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[id]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(item.expireAt) {
+		delete(s.items, id)
+		return nil, nil
+	}
+	return item.data, nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[id] = memoryItem{data: data, expireAt: time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Touch(_ context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item, ok := s.items[id]; ok {
+		item.expireAt = time.Now().Add(ttl)
+		s.items[id] = item
+	}
+	return nil
+}
+
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, id)
+	return nil
+}
+
+// Len 当前会话数(含未清理的过期会话)
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}